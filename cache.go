@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// translationCacheDir holds one JSON file per cached LLM translation
+const translationCacheDir = "translation_cache"
+
+// cachedTranslation is a translation stored on disk, keyed by everything that determines it
+type cachedTranslation struct {
+	Key         string    `json:"key"`
+	Model       string    `json:"model"`
+	CreatedAt   time.Time `json:"created_at"`
+	InputChars  int       `json:"input_chars"`
+	Translation string    `json:"translation"`
+}
+
+// translationCache is an on-disk cache of LLM translations
+type translationCache struct {
+	Dir string
+}
+
+// newTranslationCache returns the cache in the default directory
+func newTranslationCache() *translationCache {
+	return &translationCache{Dir: translationCacheDir}
+}
+
+// translationCacheKey identifies a translation by the prompts, the model and the input text.
+// A pipeline that splits the input into chunks gets one entry per chunk by keying each chunk's text.
+func translationCacheKey(systemPrompt, prefixPrompt, model, input string) string {
+	return hashParts(systemPrompt, prefixPrompt, model, input)
+}
+
+func (c *translationCache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// Get returns the cached translation for key, or nil when there is none
+func (c *translationCache) Get(key string) (*cachedTranslation, error) {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entry cachedTranslation
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, fmt.Errorf("unable to parse cache entry %s: %v", key, err)
+	}
+	return &entry, nil
+}
+
+// Put stores a translation
+func (c *translationCache) Put(entry *cachedTranslation) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temp file and rename, so an interrupted run never leaves a truncated entry
+	tmp := c.path(entry.Key) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path(entry.Key))
+}
+
+// List returns all cached translations, newest first
+func (c *translationCache) List() ([]*cachedTranslation, error) {
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var entries []*cachedTranslation
+	for _, file := range files {
+		entry, err := c.Get(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// Clear removes all cached translations and returns how many were removed
+func (c *translationCache) Clear() (int, error) {
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return 0, err
+		}
+	}
+	return len(files), nil
+}
+
+// shortCacheKey returns the first 16 characters of a cache key for listing
+func shortCacheKey(key string) string {
+	if len(key) > 16 {
+		return key[:16]
+	}
+	return key
+}
+
+// runCacheCommand implements the cache list/clear command group
+func runCacheCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: go run main.go cache list|clear")
+		os.Exit(1)
+	}
+	cache := newTranslationCache()
+
+	switch args[0] {
+	case "list":
+		entries, err := cache.List()
+		if err != nil {
+			logFatal("unable to read translation cache", "err", err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("Translation cache is empty.")
+			return
+		}
+		fmt.Printf("%-16s  %-19s  %-10s  %10s  %12s\n", "KEY", "CREATED", "MODEL", "INPUT", "TRANSLATION")
+		for _, entry := range entries {
+			fmt.Printf("%-16s  %-19s  %-10s  %10d  %12d\n",
+				shortCacheKey(entry.Key), entry.CreatedAt.Local().Format("2006-01-02 15:04:05"), entry.Model,
+				entry.InputChars, len([]rune(entry.Translation)))
+		}
+	case "clear":
+		n, err := cache.Clear()
+		if err != nil {
+			logFatal("unable to clear translation cache", "err", err)
+		}
+		fmt.Printf("Removed %d cached translation(s)\n", n)
+	default:
+		fmt.Printf("Unknown cache command: %s\n", args[0])
+		fmt.Println("Usage: go run main.go cache list|clear")
+		os.Exit(1)
+	}
+}
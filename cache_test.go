@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTranslationCacheKey(t *testing.T) {
+	base := translationCacheKey("system", "prefix", "grok-4", "input")
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"System prompt", translationCacheKey("system2", "prefix", "grok-4", "input")},
+		{"Prefix prompt", translationCacheKey("system", "prefix2", "grok-4", "input")},
+		{"Model", translationCacheKey("system", "prefix", "grok-3", "input")},
+		{"Input", translationCacheKey("system", "prefix", "grok-4", "input2")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.key == base {
+				t.Errorf("changing the %s does not change the key", tt.name)
+			}
+		})
+	}
+	if base != translationCacheKey("system", "prefix", "grok-4", "input") {
+		t.Error("translationCacheKey() is not deterministic")
+	}
+}
+
+func TestTranslationCache(t *testing.T) {
+	cache := &translationCache{Dir: t.TempDir()}
+
+	if entry, err := cache.Get("missing"); err != nil || entry != nil {
+		t.Fatalf("Get() of a missing key = %+v, %v", entry, err)
+	}
+
+	older := &cachedTranslation{Key: "a", Model: "grok-4", CreatedAt: time.Now().Add(-time.Hour), Translation: "旧"}
+	newer := &cachedTranslation{Key: "b", Model: "grok-4", CreatedAt: time.Now(), Translation: "新"}
+	for _, entry := range []*cachedTranslation{older, newer} {
+		if err := cache.Put(entry); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	if tmp, _ := filepath.Glob(filepath.Join(cache.Dir, "*.tmp")); len(tmp) != 0 {
+		t.Errorf("Put() left temp files behind: %v", tmp)
+	}
+
+	got, err := cache.Get("a")
+	if err != nil || got == nil || got.Translation != "旧" {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+
+	entries, err := cache.List()
+	if err != nil || len(entries) != 2 || entries[0].Key != "b" {
+		t.Fatalf("List() = %+v, %v, want newest first", entries, err)
+	}
+
+	n, err := cache.Clear()
+	if err != nil || n != 2 {
+		t.Fatalf("Clear() = %d, %v", n, err)
+	}
+	if entries, _ := cache.List(); len(entries) != 0 {
+		t.Errorf("List() after Clear() = %+v", entries)
+	}
+}
+
+func TestShortCacheKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"0123456789abcdef0123", "0123456789abcdef"},
+		{"0123456789abcdef", "0123456789abcdef"},
+		{"short", "short"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if result := shortCacheKey(tt.key); result != tt.expected {
+			t.Errorf("shortCacheKey(%q) = %q, want %q", tt.key, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// CompareIssueKind identifies a category of structural drift between source and target
+type CompareIssueKind string
+
+const (
+	IssueMissingInTarget    CompareIssueKind = "missing_in_target"
+	IssueExtraInTarget      CompareIssueKind = "extra_in_target"
+	IssueUntranslated       CompareIssueKind = "untranslated"
+	IssueConsecutiveChinese CompareIssueKind = "consecutive_chinese"
+	IssueMixedLine          CompareIssueKind = "mixed_line"
+)
+
+// compareIssueOrder lists issue kinds in the order they are reported
+var compareIssueOrder = []CompareIssueKind{
+	IssueMissingInTarget,
+	IssueExtraInTarget,
+	IssueUntranslated,
+	IssueConsecutiveChinese,
+	IssueMixedLine,
+}
+
+// compareIssueTitles holds the human-readable heading for each issue kind
+var compareIssueTitles = map[CompareIssueKind]string{
+	IssueMissingInTarget:    "Source English lines missing in target",
+	IssueExtraInTarget:      "Extra English lines in target",
+	IssueUntranslated:       "English lines without a following Chinese line",
+	IssueConsecutiveChinese: "Consecutive Chinese lines",
+	IssueMixedLine:          "Mixed English/Chinese lines",
+}
+
+// DocumentLine is a non-empty line read from a document
+type DocumentLine struct {
+	Number int
+	Text   string
+	Type   LineType
+	Key    string
+}
+
+// CompareIssue is a single structural difference found by compare
+type CompareIssue struct {
+	Kind       CompareIssueKind
+	SourceLine int // 0 when the issue only concerns the target
+	TargetLine int // 0 when the issue only concerns the source
+	Text       string
+}
+
+// CompareReport summarizes how a bilingual target differs from its source
+type CompareReport struct {
+	SourceLineCount int
+	TargetLineCount int
+	Issues          []CompareIssue
+}
+
+// HasProblems reports whether any structural drift was found
+func (r *CompareReport) HasProblems() bool {
+	return len(r.Issues) > 0
+}
+
+// IssuesOfKind returns the issues of a single category in document order
+func (r *CompareReport) IssuesOfKind(kind CompareIssueKind) []CompareIssue {
+	var issues []CompareIssue
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// compareDocuments reports the structural drift between a source and its bilingual target
+func compareDocuments(c *clients, sourceURL, targetURL, reportPath string) {
+	sourceDocID := extractDocumentID(sourceURL)
+	targetDocID := extractDocumentID(targetURL)
+
+	if sourceDocID == "" {
+		logFatal("invalid source Google Docs URL, please provide a valid document URL", "url", sourceURL)
+	}
+	if targetDocID == "" {
+		logFatal("invalid target Google Docs URL, please provide a valid document URL", "url", targetURL)
+	}
+
+	slog.Info("comparing documents", "source_doc_id", sourceDocID, "target_doc_id", targetDocID)
+
+	var htmlReport *SyncReport
+	if reportPath != "" {
+		htmlReport = newSyncReport("Compare Report", sourceDocID, targetDocID)
+	}
+
+	report, err := loadAndCompareDocuments(context.Background(), c.Docs, sourceDocID, targetDocID, htmlReport)
+	if err != nil {
+		logFatal("error comparing documents", "err", err)
+	}
+
+	printCompareReport(report)
+
+	if htmlReport != nil {
+		if err := writeSyncReportHTML(reportPath, htmlReport); err != nil {
+			logFatal("error writing HTML report", "err", err)
+		}
+		slog.Info("HTML report written", "path", reportPath)
+	}
+
+	if report.HasProblems() {
+		os.Exit(1)
+	}
+}
+
+// loadAndCompareDocuments fetches both documents and compares their lines, filling htmlReport when it is not nil
+func loadAndCompareDocuments(ctx context.Context, docsService *docs.Service, sourceDocID, targetDocID string, htmlReport *SyncReport) (*CompareReport, error) {
+	sourceDoc, err := getDocument(ctx, docsService, sourceDocID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve source document: %v", err)
+	}
+
+	targetDoc, err := getDocument(ctx, docsService, targetDocID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve target document: %v", err)
+	}
+
+	sourceLines, err := collectDocumentLines(sourceDoc)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source document: %v", err)
+	}
+	targetLines, err := collectDocumentLines(targetDoc)
+	if err != nil {
+		return nil, fmt.Errorf("error reading target document: %v", err)
+	}
+
+	if htmlReport != nil {
+		buildCompareReportRows(htmlReport, sourceLines, targetLines)
+	}
+
+	return compareDocumentLines(sourceLines, targetLines), nil
+}
+
+// collectDocumentLines reads every non-empty line of a document, numbered like the sync loop does
+func collectDocumentLines(doc *docs.Document) ([]DocumentLine, error) {
+	cursor := &DocumentCursor{Document: doc, ElementIndex: 0, LineIndex: 0}
+	var lines []DocumentLine
+	for {
+		lineInfo, err := getNextNonEmptyLine(cursor)
+		if err != nil {
+			if err.Error() == "end of document" {
+				break
+			}
+			return nil, err
+		}
+		lines = append(lines, DocumentLine{
+			Number: len(lines) + 1,
+			Text:   lineInfo.Text,
+			Type:   classifyLineType(lineInfo.Text),
+			Key:    generateLineKey(lineInfo.Text),
+		})
+	}
+	return lines, nil
+}
+
+// isTranslationLine reports whether a line type is treated as a translation by the sync loop
+func isTranslationLine(lineType LineType) bool {
+	return lineType == LineTypeChinese || lineType == LineTypeMixed
+}
+
+// compareDocumentLines aligns the English lines of source and target and reports structural drift
+func compareDocumentLines(sourceLines, targetLines []DocumentLine) *CompareReport {
+	report := &CompareReport{
+		SourceLineCount: len(sourceLines),
+		TargetLineCount: len(targetLines),
+	}
+
+	// The source only has English lines; the target's English side is everything that isn't a translation
+	var targetEnglish []DocumentLine
+	for _, line := range targetLines {
+		if !isTranslationLine(line.Type) {
+			targetEnglish = append(targetEnglish, line)
+		}
+	}
+
+	sourceMatch, targetMatch := alignLineKeys(sourceLines, targetEnglish)
+	for i, line := range sourceLines {
+		if sourceMatch[i] < 0 {
+			report.Issues = append(report.Issues, CompareIssue{Kind: IssueMissingInTarget, SourceLine: line.Number, Text: line.Text})
+		}
+	}
+	for i, line := range targetEnglish {
+		if targetMatch[i] < 0 {
+			report.Issues = append(report.Issues, CompareIssue{Kind: IssueExtraInTarget, TargetLine: line.Number, Text: line.Text})
+		}
+	}
+
+	for i, line := range targetLines {
+		switch {
+		case !isTranslationLine(line.Type):
+			if i+1 >= len(targetLines) || !isTranslationLine(targetLines[i+1].Type) {
+				report.Issues = append(report.Issues, CompareIssue{Kind: IssueUntranslated, TargetLine: line.Number, Text: line.Text})
+			}
+		case line.Type == LineTypeMixed:
+			report.Issues = append(report.Issues, CompareIssue{Kind: IssueMixedLine, TargetLine: line.Number, Text: line.Text})
+		}
+		if line.Type == LineTypeChinese && i > 0 && targetLines[i-1].Type == LineTypeChinese {
+			report.Issues = append(report.Issues, CompareIssue{Kind: IssueConsecutiveChinese, TargetLine: line.Number, Text: line.Text})
+		}
+	}
+
+	return report
+}
+
+// alignLineKeys computes the longest common subsequence of line keys and returns, for each line,
+// the index of its partner in the other slice or -1 when it is unmatched
+func alignLineKeys(a, b []DocumentLine) ([]int, []int) {
+	// lcs[i][j] holds the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if linesMatch(a[i].Key, b[j].Key) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	aMatch := make([]int, len(a))
+	for i := range aMatch {
+		aMatch[i] = -1
+	}
+	bMatch := make([]int, len(b))
+	for j := range bMatch {
+		bMatch[j] = -1
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case linesMatch(a[i].Key, b[j].Key):
+			aMatch[i] = j
+			bMatch[j] = i
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return aMatch, bMatch
+}
+
+// truncateText shortens text to at most max runes for display
+func truncateText(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "..."
+}
+
+// printCompareReport prints the compare summary grouped by issue kind
+func printCompareReport(report *CompareReport) {
+	fmt.Println("\n=== Document Comparison Summary ===")
+	fmt.Printf("Source lines: %d\n", report.SourceLineCount)
+	fmt.Printf("Target lines: %d\n", report.TargetLineCount)
+
+	for _, kind := range compareIssueOrder {
+		issues := report.IssuesOfKind(kind)
+		fmt.Printf("\n%s: %d\n", compareIssueTitles[kind], len(issues))
+		for _, issue := range issues {
+			if issue.SourceLine > 0 {
+				fmt.Printf("  source line %d: %s\n", issue.SourceLine, truncateText(issue.Text, 80))
+			} else {
+				fmt.Printf("  target line %d: %s\n", issue.TargetLine, truncateText(issue.Text, 80))
+			}
+		}
+	}
+
+	fmt.Println("===================================")
+	if report.HasProblems() {
+		fmt.Printf("Found %d problem(s)\n", len(report.Issues))
+	} else {
+		fmt.Println("No structural problems found")
+	}
+}
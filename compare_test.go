@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+)
+
+// buildDocumentLines numbers and classifies plain text lines the way collectDocumentLines does
+func buildDocumentLines(texts ...string) []DocumentLine {
+	lines := make([]DocumentLine, len(texts))
+	for i, text := range texts {
+		lines[i] = DocumentLine{Number: i + 1, Text: text, Type: classifyLineType(text), Key: generateLineKey(text)}
+	}
+	return lines
+}
+
+func TestCompareDocumentLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   []DocumentLine
+		target   []DocumentLine
+		expected []CompareIssue
+	}{
+		{
+			name:     "Perfectly paired target",
+			source:   buildDocumentLines("Morning Service", "Pastor Alan Fong"),
+			target:   buildDocumentLines("Morning Service", "早晨崇拜", "Pastor Alan Fong", "方牧师"),
+			expected: nil,
+		},
+		{
+			name:   "Missing source line",
+			source: buildDocumentLines("Morning Service", "Call to Worship", "Pastor Alan Fong"),
+			target: buildDocumentLines("Morning Service", "早晨崇拜", "Pastor Alan Fong", "方牧师"),
+			expected: []CompareIssue{
+				{Kind: IssueMissingInTarget, SourceLine: 2, Text: "Call to Worship"},
+			},
+		},
+		{
+			name:   "Extra untranslated target line",
+			source: buildDocumentLines("Morning Service"),
+			target: buildDocumentLines("Morning Service", "早晨崇拜", "Amen"),
+			expected: []CompareIssue{
+				{Kind: IssueExtraInTarget, TargetLine: 3, Text: "Amen"},
+				{Kind: IssueUntranslated, TargetLine: 3, Text: "Amen"},
+			},
+		},
+		{
+			name:   "Consecutive Chinese and mixed lines",
+			source: buildDocumentLines("Pastor Alan Fong"),
+			target: buildDocumentLines("Pastor Alan Fong", "牧师 Alan Fong", "方牧师", "方牧师"),
+			expected: []CompareIssue{
+				{Kind: IssueMixedLine, TargetLine: 2, Text: "牧师 Alan Fong"},
+				{Kind: IssueConsecutiveChinese, TargetLine: 4, Text: "方牧师"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := compareDocumentLines(tt.source, tt.target)
+			if len(report.Issues) != len(tt.expected) {
+				t.Fatalf("got %d issues %+v, want %d %+v", len(report.Issues), report.Issues, len(tt.expected), tt.expected)
+			}
+			for i, issue := range report.Issues {
+				if issue != tt.expected[i] {
+					t.Errorf("issue %d = %+v, want %+v", i, issue, tt.expected[i])
+				}
+			}
+			if report.HasProblems() != (len(tt.expected) > 0) {
+				t.Errorf("HasProblems() = %v, want %v", report.HasProblems(), len(tt.expected) > 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"gopkg.in/yaml.v3"
+)
+
+// Default file names, looked up in the config directory (the working directory when unset)
+const (
+	defaultConfigFile      = "application.yaml"
+	defaultCredentialsFile = "churchoutline.json"
+	defaultOAuthClientFile = "client_json"
+	defaultTokenFile       = "token.json"
+	defaultXAIKeyFile      = "grokkey"
+)
+
+// appConfig is the contents of application.yaml
+type appConfig struct {
+	Input                 string        `yaml:"input"`
+	OutputName            string        `yaml:"output_name"`
+	OutputFolderID        string        `yaml:"output_folder_id"`
+	OnExisting            string        `yaml:"on_existing"`
+	OutputMode            string        `yaml:"output_mode"`
+	GlossaryFile          string        `yaml:"glossary_file"`
+	BibleFile             string        `yaml:"bible_file"`
+	TranslationMemoryFile string        `yaml:"translation_memory_file"`
+	Sharing               sharingConfig `yaml:"sharing"`
+	Auth                  authConfig    `yaml:"auth"`
+}
+
+// authConfig is the optional auth section of application.yaml
+type authConfig struct {
+	ConfigDir                     string `yaml:"config_dir"`
+	CredentialsFile               string `yaml:"credentials_file"`
+	OAuthClientFile               string `yaml:"oauth_client_file"`
+	TokenFile                     string `yaml:"token_file"`
+	XAIKeyFile                    string `yaml:"xai_key_file"`
+	ApplicationDefaultCredentials bool   `yaml:"application_default_credentials"`
+}
+
+// authFlags holds the global command-line overrides for the auth configuration
+type authFlags struct {
+	ConfigFile                    string
+	ConfigDir                     string
+	CredentialsFile               string
+	OAuthClientFile               string
+	TokenFile                     string
+	ApplicationDefaultCredentials bool
+}
+
+// authSettings is the resolved location of every credential used by the tool
+type authSettings struct {
+	CredentialsFile string // service account key for the Docs API; empty when application-default credentials are used
+	OAuthClientFile string // OAuth client config for creating docs as the user
+	TokenFile       string // OAuth token cache
+	XAIKey          string // xAI API key from the environment, if set
+	XAIKeyFile      string // file holding the xAI API key otherwise
+}
+
+// loadAppConfig reads application.yaml; a missing file yields an empty config so
+// commands that don't need it keep working
+func loadAppConfig(path string) (*appConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &appConfig{}, nil
+		}
+		return nil, err
+	}
+	var cfg appConfig
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// firstNonEmpty returns the first non-blank value
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// resolveAuth determines where every credential comes from. Precedence is command-line
+// flags, then environment variables, then application.yaml, then the config directory.
+func resolveAuth(flags authFlags, cfg authConfig, getenv func(string) string, exists func(string) bool) authSettings {
+	configDir := firstNonEmpty(flags.ConfigDir, cfg.ConfigDir)
+	inConfigDir := func(name string) string {
+		if configDir == "" {
+			return name
+		}
+		return filepath.Join(configDir, name)
+	}
+
+	settings := authSettings{
+		OAuthClientFile: firstNonEmpty(flags.OAuthClientFile, cfg.OAuthClientFile, inConfigDir(defaultOAuthClientFile)),
+		TokenFile:       firstNonEmpty(flags.TokenFile, cfg.TokenFile, inConfigDir(defaultTokenFile)),
+		XAIKey:          strings.TrimSpace(getenv("XAI_API_KEY")),
+		XAIKeyFile:      firstNonEmpty(cfg.XAIKeyFile, inConfigDir(defaultXAIKeyFile)),
+	}
+
+	if flags.ApplicationDefaultCredentials || cfg.ApplicationDefaultCredentials {
+		return settings
+	}
+
+	explicit := firstNonEmpty(flags.CredentialsFile, getenv("GOOGLE_APPLICATION_CREDENTIALS"), cfg.CredentialsFile)
+	if explicit != "" {
+		settings.CredentialsFile = explicit
+		return settings
+	}
+	// Fall back to application-default credentials when the conventional key file is absent
+	if exists(inConfigDir(defaultCredentialsFile)) {
+		settings.CredentialsFile = inConfigDir(defaultCredentialsFile)
+	}
+	return settings
+}
+
+// usesApplicationDefaultCredentials reports whether Google APIs are called with ADC
+func (a authSettings) usesApplicationDefaultCredentials() bool {
+	return a.CredentialsFile == ""
+}
+
+// googleClientOption returns the client option authenticating Google API calls
+func (a authSettings) googleClientOption(ctx context.Context, scopes ...string) (option.ClientOption, error) {
+	if a.usesApplicationDefaultCredentials() {
+		creds, err := google.FindDefaultCredentials(ctx, scopes...)
+		if err != nil {
+			return nil, fmt.Errorf("no credentials file found and application-default credentials are unavailable: %v", err)
+		}
+		return option.WithCredentials(creds), nil
+	}
+	if !fileExists(a.CredentialsFile) {
+		return nil, fmt.Errorf("%s not found. Please follow setup instructions in README.md", a.CredentialsFile)
+	}
+	return option.WithCredentialsFile(a.CredentialsFile), nil
+}
+
+// xaiKey returns the xAI API key from the environment or the key file
+func (a authSettings) xaiKey() (string, error) {
+	if a.XAIKey != "" {
+		return a.XAIKey, nil
+	}
+	b, err := os.ReadFile(a.XAIKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("XAI_API_KEY is not set and %s could not be read: %w", a.XAIKeyFile, err)
+	}
+	key := strings.TrimSpace(string(b))
+	if key == "" {
+		return "", fmt.Errorf("%s is empty", a.XAIKeyFile)
+	}
+	return key, nil
+}
+
+// serviceAccountEmail returns the client_email of the service account key in use
+func (a authSettings) serviceAccountEmail() (string, error) {
+	if a.usesApplicationDefaultCredentials() {
+		return "", errors.New("application-default credentials are in use")
+	}
+	return extractServiceAccountEmail(a.CredentialsFile)
+}
+
+// clients holds the Google API services shared by all commands, built once per run
+type clients struct {
+	Auth authSettings
+	Docs *docs.Service
+
+	mu           sync.Mutex
+	serviceDrive *drive.Service
+	userDrive    *drive.Service
+}
+
+// newClients builds the Docs service from the resolved credentials
+func newClients(ctx context.Context, auth authSettings) (*clients, error) {
+	opt, err := auth.googleClientOption(ctx, docs.DocumentsScope, drive.DriveFileScope)
+	if err != nil {
+		return nil, err
+	}
+	docsService, err := docs.NewService(ctx, opt, option.WithScopes(docs.DocumentsScope, drive.DriveFileScope))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Docs service: %v", err)
+	}
+	return &clients{Auth: auth, Docs: docsService}, nil
+}
+
+// ServiceDrive returns a Drive service acting as the service account, creating it on first use
+func (c *clients) ServiceDrive(ctx context.Context) (*drive.Service, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.serviceDrive != nil {
+		return c.serviceDrive, nil
+	}
+	opt, err := c.Auth.googleClientOption(ctx, drive.DriveFileScope)
+	if err != nil {
+		return nil, err
+	}
+	srv, err := drive.NewService(ctx, opt, option.WithScopes(drive.DriveFileScope))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Drive service: %v", err)
+	}
+	c.serviceDrive = srv
+	return srv, nil
+}
+
+// UserDrive returns a Drive service acting as the OAuth user, creating it on first use
+func (c *clients) UserDrive(ctx context.Context) (*drive.Service, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.userDrive != nil {
+		return c.userDrive, nil
+	}
+	client, err := c.userHTTPClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	srv, err := drive.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Drive service: %w", err)
+	}
+	c.userDrive = srv
+	return srv, nil
+}
+
+// userHTTPClient returns an HTTP client authorized as the OAuth user
+func (c *clients) userHTTPClient(ctx context.Context) (*http.Client, error) {
+	config, err := loadOAuthConfig(c.Auth.OAuthClientFile)
+	if err != nil {
+		return nil, err
+	}
+	return getOAuthClient(ctx, config, c.Auth.TokenFile)
+}
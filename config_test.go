@@ -0,0 +1,119 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveAuth(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+	existing := func(paths ...string) func(string) bool {
+		return func(path string) bool {
+			for _, p := range paths {
+				if p == path {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	tests := []struct {
+		name            string
+		flags           authFlags
+		cfg             authConfig
+		env             map[string]string
+		exists          []string
+		wantCredentials string
+		wantToken       string
+		wantXAIKeyFile  string
+	}{
+		{
+			name:            "Legacy defaults in working directory",
+			exists:          []string{"churchoutline.json"},
+			wantCredentials: "churchoutline.json",
+			wantToken:       "token.json",
+			wantXAIKeyFile:  "grokkey",
+		},
+		{
+			name:            "Missing key file falls back to application-default credentials",
+			wantCredentials: "",
+			wantToken:       "token.json",
+			wantXAIKeyFile:  "grokkey",
+		},
+		{
+			name:            "Config directory",
+			cfg:             authConfig{ConfigDir: "/etc/hbc"},
+			exists:          []string{filepath.Join("/etc/hbc", "churchoutline.json")},
+			wantCredentials: filepath.Join("/etc/hbc", "churchoutline.json"),
+			wantToken:       filepath.Join("/etc/hbc", "token.json"),
+			wantXAIKeyFile:  filepath.Join("/etc/hbc", "grokkey"),
+		},
+		{
+			name:            "Flag overrides config directory from yaml",
+			flags:           authFlags{ConfigDir: "/flag"},
+			cfg:             authConfig{ConfigDir: "/yaml"},
+			wantCredentials: "",
+			wantToken:       filepath.Join("/flag", "token.json"),
+			wantXAIKeyFile:  filepath.Join("/flag", "grokkey"),
+		},
+		{
+			name:            "Environment overrides yaml",
+			cfg:             authConfig{CredentialsFile: "yaml.json", TokenFile: "yaml-token.json"},
+			env:             map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": "env.json"},
+			wantCredentials: "env.json",
+			wantToken:       "yaml-token.json",
+			wantXAIKeyFile:  "grokkey",
+		},
+		{
+			name:            "Flag overrides environment",
+			flags:           authFlags{CredentialsFile: "flag.json"},
+			env:             map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": "env.json"},
+			wantCredentials: "flag.json",
+			wantToken:       "token.json",
+			wantXAIKeyFile:  "grokkey",
+		},
+		{
+			name:            "Application-default credentials requested",
+			cfg:             authConfig{ApplicationDefaultCredentials: true, CredentialsFile: "yaml.json"},
+			exists:          []string{"churchoutline.json", "yaml.json"},
+			wantCredentials: "",
+			wantToken:       "token.json",
+			wantXAIKeyFile:  "grokkey",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveAuth(tt.flags, tt.cfg, env(tt.env), existing(tt.exists...))
+			if got.CredentialsFile != tt.wantCredentials {
+				t.Errorf("CredentialsFile = %q, want %q", got.CredentialsFile, tt.wantCredentials)
+			}
+			if got.TokenFile != tt.wantToken {
+				t.Errorf("TokenFile = %q, want %q", got.TokenFile, tt.wantToken)
+			}
+			if got.XAIKeyFile != tt.wantXAIKeyFile {
+				t.Errorf("XAIKeyFile = %q, want %q", got.XAIKeyFile, tt.wantXAIKeyFile)
+			}
+		})
+	}
+}
+
+func TestXAIKeyFromEnvironment(t *testing.T) {
+	auth := resolveAuth(authFlags{}, authConfig{}, func(key string) string {
+		if key == "XAI_API_KEY" {
+			return " secret \n"
+		}
+		return ""
+	}, func(string) bool { return false })
+
+	key, err := auth.xaiKey()
+	if err != nil {
+		t.Fatalf("xaiKey() error = %v", err)
+	}
+	if key != "secret" {
+		t.Errorf("xaiKey() = %q, want %q", key, "secret")
+	}
+}
@@ -3,29 +3,13 @@ package main
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"google.golang.org/api/docs/v1"
-	"google.golang.org/api/option"
 )
 
-func getFirstLineFromDoc(docID string) (string, error) {
-	ctx := context.Background()
-
-	// Load credentials from file
-	credentialsFile := "churchoutline.json"
-	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
-		return "", fmt.Errorf("churchoutline.json not found. Please follow setup instructions in README.md")
-	}
-
-	// Create Docs service using credentials file directly
-	docsService, err := docs.NewService(ctx, option.WithCredentialsFile(credentialsFile), option.WithScopes(docs.DocumentsReadonlyScope))
-	if err != nil {
-		return "", fmt.Errorf("unable to create Docs service: %v", err)
-	}
-
+func getFirstLineFromDoc(ctx context.Context, docsService *docs.Service, docID string) (string, error) {
 	// Get the document
-	doc, err := docsService.Documents.Get(docID).Do()
+	doc, err := getDocument(ctx, docsService, docID)
 	if err != nil {
 		return "", fmt.Errorf("unable to retrieve document: %v", err)
 	}
@@ -8,25 +8,67 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 	"google.golang.org/api/docs/v1"
 	"google.golang.org/api/drive/v3"
-	"google.golang.org/api/option"
-	"gopkg.in/yaml.v3"
 )
 
-type e2eConfig struct {
-	Input      string `yaml:"input"`
-	OutputName string `yaml:"output_name"`
+// Exit codes of the e2e command, so schedulers can tell a reviewer's decision from a failure.
+// The review codes stay clear of 2, which Go also uses for a panic.
+const (
+	ExitError          = 1
+	ExitReviewAborted  = 10
+	ExitReviewTimedOut = 11
+)
+
+// e2eOptions holds the e2e command-line flags
+type e2eOptions struct {
+	Yes            bool          // skip the review gate
+	StopAfterWrite bool          // exit after writing the translation, before formatting
+	ReviewTimeout  time.Duration // abort when nobody confirms in time; 0 waits forever
+	ResumeRunID    string        // continue an earlier run instead of starting a new one
+	NoCache        bool          // always call the LLM instead of reusing a cached translation
+}
+
+// validateE2EOptions rejects flag combinations the e2e command can't honour. The review gate
+// flags exclude each other, so a scheduled job never depends on which one silently wins.
+func validateE2EOptions(opts e2eOptions, mode string) error {
+	if opts.ResumeRunID != "" && mode != "" {
+		return fmt.Errorf("--mode cannot be changed when resuming a run")
+	}
+	if opts.ReviewTimeout < 0 {
+		return fmt.Errorf("--review-timeout must not be negative")
+	}
+	gates := 0
+	for _, set := range []bool{opts.Yes, opts.StopAfterWrite, opts.ReviewTimeout > 0} {
+		if set {
+			gates++
+		}
+	}
+	if gates > 1 {
+		return fmt.Errorf("--yes, --stop-after-write and --review-timeout cannot be combined")
+	}
+	return nil
 }
 
+// grokModel is the xAI model used for translation
+const grokModel = "grok-4"
+
+// reviewOutcome is the result of the review gate
+type reviewOutcome int
+
+const (
+	reviewApproved reviewOutcome = iota
+	reviewAborted
+	reviewTimedOut
+)
+
 type grokMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
@@ -37,197 +79,306 @@ type grokRequest struct {
 	Model string        `json:"model"`
 }
 
-func runE2E() {
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-
-	cfg, err := loadE2EConfig("application.yaml")
+func runE2E(c *clients, cfg *appConfig, opts e2eOptions) {
+	tr, err := newTranslator(cfg, c.Auth, opts.NoCache)
 	if err != nil {
-		log.Fatalf("failed to load application.yaml: %v", err)
-	}
-	cfg.Input = strings.TrimSpace(cfg.Input)
-	cfg.OutputName = strings.TrimSpace(cfg.OutputName)
-	if cfg.Input == "" || cfg.OutputName == "" {
-		log.Fatalf("application.yaml must include non-empty input and output_name")
+		logFatal("failed to set up translation", "err", err)
 	}
 
-	systemPrompt, err := readTextFile("system_prompt")
-	if err != nil {
-		log.Fatalf("failed to read system_prompt: %v", err)
+	if _, err := buildSharingPermissions(cfg.Sharing); err != nil {
+		logFatal("invalid sharing config in application.yaml", "err", err)
 	}
-	prefixPrompt, err := readTextFile("prefix_prompt")
-	if err != nil {
-		log.Fatalf("failed to read prefix_prompt: %v", err)
-	}
-	grokKey, err := readTextFile("grokkey")
+	onExisting, err := validateOnExisting(cfg.OnExisting)
 	if err != nil {
-		log.Fatalf("failed to read grokkey: %v", err)
-	}
-	grokKey = strings.TrimSpace(grokKey)
-	if grokKey == "" {
-		log.Fatalf("grokkey is empty")
+		logFatal("invalid application.yaml", "err", err)
 	}
 
-	ctx := context.Background()
-
-	created, driveSrv, err := createGoogleDocWithPublicEdit(ctx, "client_json", "token.json", cfg.OutputName)
-	if err != nil {
-		log.Fatalf("failed to create output doc: %v", err)
-	}
-	outputDocID := created.Id
-	outputURL := fmt.Sprintf("https://docs.google.com/document/d/%s/edit", outputDocID)
-	log.Printf("STEP 1 OK: created output doc: %s", outputURL)
-
-	inputDocID := extractDocumentID(cfg.Input)
-	if inputDocID == "" {
-		log.Fatalf("failed to parse input doc id from url: %s", cfg.Input)
+	var manifest *runManifest
+	if opts.ResumeRunID != "" {
+		manifest, err = loadRunManifest(opts.ResumeRunID)
+		if err != nil {
+			logFatal("failed to load run manifest", "err", err)
+		}
+		slog.Info("resuming run", "run_id", manifest.RunID, "completed_steps", manifest.CompletedSteps)
+	} else {
+		cfg.Input = strings.TrimSpace(cfg.Input)
+		cfg.OutputName = strings.TrimSpace(cfg.OutputName)
+		if cfg.Input == "" || cfg.OutputName == "" {
+			logFatal("application.yaml must include non-empty input and output_name")
+		}
+		outputMode, err := validateOutputMode(cfg.OutputMode)
+		if err != nil {
+			logFatal("invalid output mode", "err", err)
+		}
+		inputURL, inputFile := cfg.Input, ""
+		if isLocalInput(cfg.Input) {
+			inputFile = cfg.Input
+			inputURL, err = importLocalInput(context.Background(), c, inputFile, cfg.OutputFolderID)
+			if err != nil {
+				logFatal("failed to import local input file", "path", inputFile, "err", err)
+			}
+			slog.Info("STEP 0 OK: imported local input file as a Google Doc", "path", inputFile, "url", inputURL)
+		}
+		inputDocID := extractDocumentID(inputURL)
+		if inputDocID == "" {
+			logFatal("failed to parse input doc id from url", "url", inputURL)
+		}
+		runID, err := newRunID(time.Now())
+		if err != nil {
+			logFatal("failed to create run id", "err", err)
+		}
+		manifest = &runManifest{RunID: runID, CreatedAt: time.Now(), Input: inputURL, InputFile: inputFile, InputDocID: inputDocID, OutputMode: outputMode}
 	}
+	outputMode := manifest.OutputMode
 
-	serviceAccountEmail, err := extractServiceAccountEmail("churchoutline.json")
-	if err != nil {
-		log.Fatalf("failed to read service account email from churchoutline.json: %v", err)
-	}
-	if err := grantWriterToServiceAccount(driveSrv, outputDocID, serviceAccountEmail); err != nil {
-		log.Fatalf("failed to grant service account writer permission on output doc: %v", err)
-	}
-	log.Printf("STEP 1.1 OK: granted service account writer access")
+	ctx := context.Background()
 
-	inputText, err := readGoogleDocPlainText(ctx, "churchoutline.json", inputDocID)
+	inputTitle, inputRevision, err := getDocumentInfo(ctx, c.Docs, manifest.InputDocID)
 	if err != nil {
-		log.Fatalf("failed to read input google doc: %v", err)
+		logFatal("failed to read input doc", "err", err)
 	}
-	log.Printf("STEP 2 OK: read input Google Doc content")
-
-	userContent := prefixPrompt + "\n" + inputText
-	req := grokRequest{
-		Input: []grokMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userContent},
-		},
-		Model: "grok-4",
+	hash := promptHash(tr.SystemPrompt, tr.PrefixPrompt, grokModel)
+	if manifest.isCompleted(StepTranslate) && (manifest.InputRevision != inputRevision || manifest.PromptHash != hash) {
+		if outputMode == OutputModeInterleave && manifest.isCompleted(StepWriteOutput) {
+			logFatal("the input doc or prompts changed after the translation was inserted; start a new run instead of resuming")
+		}
+		slog.Warn("the input doc or prompts changed since the cached translation, translating again")
+		manifest.invalidateFrom(StepTranslate)
 	}
-	log.Printf("STEP 3 OK: built Grok request payload")
+	manifest.InputRevision = inputRevision
+	manifest.PromptHash = hash
 
-	translation, err := callGrokResponses(ctx, grokKey, req)
-	if err != nil {
-		log.Fatalf("failed to call grok: %v", err)
+	completeStep := func(step string) {
+		manifest.markCompleted(step)
+		if err := saveRunManifest(manifest); err != nil {
+			logFatal("failed to save run manifest", "err", err)
+		}
 	}
-	if translation == "" {
-		log.Fatalf("grok returned empty translation")
+	alreadyDone := func(step, label string) bool {
+		if manifest.isCompleted(step) {
+			slog.Info(label+" SKIPPED: already completed", "run_id", manifest.RunID)
+			return true
+		}
+		return false
 	}
-	log.Printf("STEP 4 OK: received Grok response")
-
-	if err := writeGoogleDocReplaceAll(ctx, "churchoutline.json", outputDocID, translation); err != nil {
-		log.Fatalf("failed to write output google doc: %v", err)
+	if err := saveRunManifest(manifest); err != nil {
+		logFatal("failed to save run manifest", "err", err)
 	}
-	log.Printf("STEP 5 OK: wrote translation to output Google Doc")
+	slog.Info("run manifest", "run_id", manifest.RunID, "path", manifestPath(manifest.RunID))
 
-	if !waitForUserReview(outputURL) {
-		log.Fatalf("aborted")
+	if !alreadyDone(StepCreateOutput, "STEP 1") {
+		outputName, err := renderOutputName(cfg.OutputName, newOutputNameData(inputTitle, time.Now()))
+		if err != nil {
+			logFatal("failed to build output doc name", "err", err)
+		}
+		driveSrv, err := c.UserDrive(ctx)
+		if err != nil {
+			logFatal("failed to set up Drive access", "err", err)
+		}
+		outputName, err = resolveOutputName(ctx, driveSrv, outputName, cfg.OutputFolderID, onExisting)
+		if err != nil {
+			logFatal("failed to choose output doc name", "err", err)
+		}
+		var created *drive.File
+		if outputMode == OutputModeCopy || outputMode == OutputModeInterleave {
+			created, err = copyInputDoc(ctx, driveSrv, manifest.InputDocID, outputName, cfg.OutputFolderID)
+		} else {
+			created, err = createOutputDoc(ctx, driveSrv, outputName, cfg.OutputFolderID)
+		}
+		if err != nil {
+			logFatal("failed to create output doc", "err", err)
+		}
+		manifest.OutputName = outputName
+		manifest.OutputDocID = created.Id
+		manifest.OutputURL = fmt.Sprintf("https://docs.google.com/document/d/%s/edit", created.Id)
+		slog.Info("STEP 1 OK: created output doc", "name", outputName, "mode", outputMode, "url", manifest.OutputURL)
+		completeStep(StepCreateOutput)
+	}
+	outputDocID := manifest.OutputDocID
+	outputURL := manifest.OutputURL
+
+	if !alreadyDone(StepGrantServiceAccount, "STEP 1.1") {
+		if c.Auth.usesApplicationDefaultCredentials() {
+			slog.Info("STEP 1.1 SKIPPED: using application-default credentials, no service account to grant access to")
+		} else {
+			serviceAccountEmail, err := c.Auth.serviceAccountEmail()
+			if err != nil {
+				logFatal("failed to read service account email", "credentials", c.Auth.CredentialsFile, "err", err)
+			}
+			driveSrv, err := c.UserDrive(ctx)
+			if err != nil {
+				logFatal("failed to set up Drive access", "err", err)
+			}
+			if err := grantWriterToServiceAccount(driveSrv, outputDocID, serviceAccountEmail); err != nil {
+				logFatal("failed to grant service account writer permission on output doc", "err", err)
+			}
+			slog.Info("STEP 1.1 OK: granted service account writer access")
+		}
+		completeStep(StepGrantServiceAccount)
 	}
-	log.Printf("STEP 6 OK: user confirmed review")
-
-	syncDocumentFormatting(cfg.Input, outputURL, 1)
-}
 
-func loadE2EConfig(path string) (*e2eConfig, error) {
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	var cfg e2eConfig
-	if err := yaml.Unmarshal(b, &cfg); err != nil {
-		return nil, err
+	if !alreadyDone(StepShareOutput, "STEP 1.2") {
+		driveSrv, err := c.UserDrive(ctx)
+		if err != nil {
+			logFatal("failed to set up Drive access", "err", err)
+		}
+		if err := applySharingPolicy(ctx, driveSrv, outputDocID, cfg.Sharing); err != nil {
+			logFatal("failed to apply sharing policy to output doc", "err", err)
+		}
+		slog.Info("STEP 1.2 OK: applied sharing policy")
+		completeStep(StepShareOutput)
 	}
-	return &cfg, nil
-}
 
-func readTextFile(path string) (string, error) {
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
-	}
-	return string(b), nil
-}
+	if !alreadyDone(StepTranslate, "STEPS 2-4") {
+		inputText, err := readGoogleDocPlainText(ctx, c.Docs, manifest.InputDocID)
+		if err != nil {
+			logFatal("failed to read input google doc", "err", err)
+		}
+		slog.Info("STEP 2 OK: read input Google Doc content")
+		if err := saveSourceSnapshot(ctx, c.Docs, manifest); err != nil {
+			logFatal("failed to save source snapshot", "err", err)
+		}
 
-func createGoogleDocWithPublicEdit(ctx context.Context, oauthClientPath, tokenPath, name string) (*drive.File, *drive.Service, error) {
-	b, err := os.ReadFile(oauthClientPath)
-	if err != nil {
-		return nil, nil, fmt.Errorf("unable to read oauth client file: %w", err)
+		translation, lines, err := tr.translate(ctx, inputText)
+		if err != nil {
+			logFatal("failed to translate", "err", err)
+		}
+		translationPath := filepath.Join(runDir(manifest.RunID), "translation.txt")
+		if err := os.WriteFile(translationPath, []byte(translation), 0644); err != nil {
+			logFatal("failed to save translation", "err", err)
+		}
+		manifest.TranslationFile = translationPath
+		slog.Info("STEP 4 OK: translation ready", "saved_to", translationPath)
+
+		reviewPath := filepath.Join(runDir(manifest.RunID), "review.html")
+		if err := writeSyncReportHTML(reviewPath, buildReviewReport(manifest.InputDocID, outputDocID, translation, lines)); err != nil {
+			slog.Warn("unable to write review report", "err", err)
+		} else {
+			manifest.ReviewReport = reviewPath
+		}
+		completeStep(StepTranslate)
 	}
 
-	config, err := google.ConfigFromJSON(b, drive.DriveScope)
+	translation, err := readTextFile(manifest.TranslationFile)
 	if err != nil {
-		return nil, nil, fmt.Errorf("unable to parse oauth client file to config: %w", err)
+		logFatal("failed to read cached translation", "err", err)
 	}
-
-	client, err := getOAuthClient(config, tokenPath)
-	if err != nil {
-		return nil, nil, err
+	violations := checkGlossary(parseTranslationPairs(translation), tr.Glossary)
+	for _, v := range violations {
+		slog.Warn("translation does not use glossary term", "pair", v.Pair, "term", v.Term.English, "required", v.Term.Chinese,
+			"english", truncateText(v.Line.English, 60), "chinese", truncateText(v.Line.Chinese, 60))
 	}
-
-	srv, err := drive.NewService(ctx, option.WithHTTPClient(client))
-	if err != nil {
-		return nil, nil, fmt.Errorf("unable to create Drive service: %w", err)
+	if len(tr.Glossary) > 0 {
+		slog.Info("checked glossary terms", "terms", len(tr.Glossary), "violations", len(violations))
 	}
 
-	f := &drive.File{Name: name, MimeType: "application/vnd.google-apps.document"}
-	created, err := srv.Files.Create(f).Fields("id", "name", "mimeType", "webViewLink").Do()
-	if err != nil {
-		return nil, nil, fmt.Errorf("unable to create Google Doc: %w", err)
+	if !alreadyDone(StepWriteOutput, "STEP 5") {
+		if outputMode == OutputModeInterleave {
+			plan, err := interleaveTranslation(ctx, c.Docs, outputDocID, translation)
+			if err != nil {
+				logFatal("failed to insert translation into output google doc", "err", err)
+			}
+			for _, line := range plan.Untranslated {
+				slog.Warn("source line has no translation", "line", line.Number, "text", truncateText(line.Text, 60))
+			}
+			for _, pair := range plan.Unmatched {
+				slog.Warn("translated line does not match the source", "english", truncateText(pair.English, 60), "chinese", truncateText(pair.Chinese, 60))
+			}
+			slog.Info("inserted translations", "lines", len(plan.Placements), "untranslated", len(plan.Untranslated), "unmatched", len(plan.Unmatched))
+		} else if outputMode == OutputModeTable {
+			rows, err := writeTranslationTable(ctx, c.Docs, manifest.InputDocID, outputDocID, translation)
+			if err != nil {
+				logFatal("failed to write translation table to output google doc", "err", err)
+			}
+			unformatted := 0
+			for _, row := range rows {
+				if row.Features == nil {
+					unformatted++
+					slog.Warn("translated line does not match the source, row left unformatted", "english", truncateText(row.English, 60), "chinese", truncateText(row.Chinese, 60))
+				}
+			}
+			slog.Info("wrote translation table", "rows", len(rows), "unformatted", unformatted)
+		} else {
+			if err := writeGoogleDocReplaceAll(ctx, c.Docs, outputDocID, translation); err != nil {
+				logFatal("failed to write output google doc", "err", err)
+			}
+			if outputMode == OutputModeCopy {
+				if err := resetBodyStyles(ctx, c.Docs, outputDocID); err != nil {
+					logFatal("failed to reset styles in output google doc", "err", err)
+				}
+			}
+		}
+		slog.Info("STEP 5 OK: wrote translation to output Google Doc")
+		completeStep(StepWriteOutput)
+	}
+
+	if opts.StopAfterWrite {
+		slog.Info("stopping after write as requested", "url", outputURL)
+		if outputMode == OutputModeInterleave {
+			fmt.Println("Translation inserted; English formatting was kept from the copy, nothing left to run.")
+		} else if outputMode == OutputModeTable {
+			fmt.Println("Translation table written with the source formatting, nothing left to run.")
+		} else {
+			fmt.Println("Review the output doc, then apply the formatting with:")
+			fmt.Printf("  go run . e2e --yes --resume %s\n", manifest.RunID)
+			fmt.Println("or directly with:")
+			fmt.Printf("  go run . sync-format %q %q\n", manifest.Input, outputURL)
+		}
+		return
 	}
 
-	perm := &drive.Permission{Type: "anyone", Role: "writer", AllowFileDiscovery: false}
-	_, err = srv.Permissions.Create(created.Id, perm).Fields("id").Do()
-	if err != nil {
-		return nil, nil, fmt.Errorf("unable to set sharing permission: %w", err)
+	if !alreadyDone(StepReview, "STEP 6") {
+		if opts.Yes {
+			slog.Info("STEP 6 SKIPPED: review gate disabled with --yes")
+		} else {
+			if manifest.ReviewReport != "" {
+				slog.Info("review report", "path", manifest.ReviewReport)
+			}
+			switch waitForUserReview(os.Stdin, outputURL, opts.ReviewTimeout) {
+			case reviewAborted:
+				slog.Error("aborted by reviewer", "url", outputURL, "run_id", manifest.RunID)
+				os.Exit(ExitReviewAborted)
+			case reviewTimedOut:
+				slog.Error("review timed out", "timeout", opts.ReviewTimeout, "url", outputURL, "run_id", manifest.RunID)
+				os.Exit(ExitReviewTimedOut)
+			}
+			slog.Info("STEP 6 OK: user confirmed review")
+		}
+		completeStep(StepReview)
 	}
 
-	return created, srv, nil
-}
-
-func getOAuthClient(config *oauth2.Config, tokenPath string) (*http.Client, error) {
-	tok, err := tokenFromFile(tokenPath)
-	if err != nil {
-		tok = getTokenFromWeb(config)
-		saveToken(tokenPath, tok)
+	if !alreadyDone(StepSyncFormat, "STEP 7") {
+		if outputMode == OutputModeInterleave {
+			slog.Info("English formatting was kept from the copy, skipping sync-format")
+		} else if outputMode == OutputModeTable {
+			slog.Info("table rows were formatted when written, skipping sync-format")
+		} else {
+			syncDocumentFormatting(c, manifest.Input, outputURL, 1, "")
+		}
+		completeStep(StepSyncFormat)
 	}
-	return config.Client(context.Background(), tok), nil
+	slog.Info("e2e run completed", "run_id", manifest.RunID, "url", outputURL)
 }
 
-func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("Go to the following link in your browser then type the authorization code:\n%v\n", authURL)
-
-	var authCode string
-	if _, err := fmt.Scan(&authCode); err != nil {
-		log.Fatalf("Unable to read authorization code: %v", err)
-	}
-
-	tok, err := config.Exchange(context.TODO(), authCode)
+func readTextFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatalf("Unable to retrieve token from web: %v", err)
+		return "", err
 	}
-	return tok
+	return string(b), nil
 }
 
-func tokenFromFile(file string) (*oauth2.Token, error) {
-	f, err := os.Open(file)
-	if err != nil {
-		return nil, err
+// createOutputDoc creates an empty Google Doc, inside folderID when it is set
+func createOutputDoc(ctx context.Context, srv *drive.Service, name, folderID string) (*drive.File, error) {
+	f := &drive.File{Name: name, MimeType: googleDocMimeType}
+	if folderID != "" {
+		f.Parents = []string{folderID}
 	}
-	defer f.Close()
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
-}
-
-func saveToken(path string, token *oauth2.Token) {
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	created, err := srv.Files.Create(f).Fields("id", "name", "mimeType", "webViewLink").SupportsAllDrives(true).Context(ctx).Do()
 	if err != nil {
-		log.Fatalf("Unable to cache oauth token: %v", err)
+		return nil, fmt.Errorf("unable to create Google Doc: %w", err)
 	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(token)
+	return created, nil
 }
 
 func extractServiceAccountEmail(path string) (string, error) {
@@ -253,17 +404,8 @@ func grantWriterToServiceAccount(srv *drive.Service, fileID, email string) error
 	return err
 }
 
-func readGoogleDocPlainText(ctx context.Context, credentialsPath, docID string) (string, error) {
-	if _, err := os.Stat(credentialsPath); err != nil {
-		return "", fmt.Errorf("credentials file not found: %s: %w", credentialsPath, err)
-	}
-
-	svc, err := docs.NewService(ctx, option.WithCredentialsFile(credentialsPath), option.WithScopes(docs.DocumentsReadonlyScope))
-	if err != nil {
-		return "", fmt.Errorf("unable to create docs service: %w", err)
-	}
-
-	doc, err := svc.Documents.Get(docID).Do()
+func readGoogleDocPlainText(ctx context.Context, svc *docs.Service, docID string) (string, error) {
+	doc, err := getDocument(ctx, svc, docID)
 	if err != nil {
 		return "", fmt.Errorf("unable to retrieve document: %w", err)
 	}
@@ -289,17 +431,8 @@ func readGoogleDocPlainText(ctx context.Context, credentialsPath, docID string)
 	return text, nil
 }
 
-func writeGoogleDocReplaceAll(ctx context.Context, credentialsPath, docID, newText string) error {
-	if _, err := os.Stat(credentialsPath); err != nil {
-		return fmt.Errorf("credentials file not found: %s: %w", credentialsPath, err)
-	}
-
-	svc, err := docs.NewService(ctx, option.WithCredentialsFile(credentialsPath), option.WithScopes(docs.DocumentsScope))
-	if err != nil {
-		return fmt.Errorf("unable to create docs service: %w", err)
-	}
-
-	doc, err := svc.Documents.Get(docID).Do()
+func writeGoogleDocReplaceAll(ctx context.Context, svc *docs.Service, docID, newText string) error {
+	doc, err := getDocument(ctx, svc, docID)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve document: %w", err)
 	}
@@ -330,13 +463,142 @@ func writeGoogleDocReplaceAll(ctx context.Context, credentialsPath, docID, newTe
 	}
 	reqs = append(reqs, &docs.Request{InsertText: &docs.InsertTextRequest{Location: &docs.Location{Index: 1}, Text: newText}})
 
-	_, err = svc.Documents.BatchUpdate(docID, &docs.BatchUpdateDocumentRequest{Requests: reqs}).Do()
+	_, err = batchUpdateDocument(ctx, svc, docID, &docs.BatchUpdateDocumentRequest{Requests: reqs})
 	if err != nil {
 		return fmt.Errorf("batch update failed: %w", err)
 	}
 	return nil
 }
 
+// translator holds everything needed to turn English text into bilingual text
+type translator struct {
+	Auth         authSettings
+	SystemPrompt string // includes the glossary instructions
+	PrefixPrompt string
+	Glossary     []glossaryEntry
+	Bible        bibleData
+	Memory       *translationMemory
+	NoCache      bool
+}
+
+// newTranslator reads the prompts, glossary, Bible data and translation memory named by the config
+func newTranslator(cfg *appConfig, auth authSettings, noCache bool) (*translator, error) {
+	systemPrompt, err := readTextFile("system_prompt")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read system_prompt: %v", err)
+	}
+	prefixPrompt, err := readTextFile("prefix_prompt")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read prefix_prompt: %v", err)
+	}
+	glossary, err := loadGlossary(firstNonEmpty(cfg.GlossaryFile, defaultGlossaryFile))
+	if err != nil {
+		return nil, err
+	}
+	bible, err := loadBibleData(firstNonEmpty(cfg.BibleFile, defaultBibleFile))
+	if err != nil {
+		return nil, err
+	}
+	memory, err := loadTranslationMemory(firstNonEmpty(cfg.TranslationMemoryFile, defaultTranslationMemoryFile))
+	if err != nil {
+		return nil, err
+	}
+	return &translator{
+		Auth: auth,
+		// The glossary is part of the prompt, so editing it invalidates cached translations
+		SystemPrompt: systemPrompt + glossaryPrompt(glossary),
+		PrefixPrompt: prefixPrompt,
+		Glossary:     glossary,
+		Bible:        bible,
+		Memory:       memory,
+		NoCache:      noCache,
+	}, nil
+}
+
+// translate returns the bilingual text for inputText: lines found in the translation memory are
+// reused, the rest go to Grok, and scripture lines get the Bible data's text. The returned lines
+// record which input lines came from memory.
+func (t *translator) translate(ctx context.Context, inputText string) (string, []tmLine, error) {
+	lines, unseen := prefillFromMemory(inputText, t.Memory)
+	exactReused, nearReused := countReused(lines)
+	llmInput := inputText
+	if exactReused+nearReused > 0 {
+		llmInput = unseen
+		slog.Info("translation memory", "exact", exactReused, "near", nearReused, "llm_chars", len([]rune(unseen)))
+	}
+
+	var translation string
+	if strings.TrimSpace(llmInput) == "" {
+		slog.Info("STEPS 3-4 SKIPPED: every line was found in the translation memory")
+	} else {
+		var err error
+		translation, err = translateWithCache(ctx, t.Auth, t.SystemPrompt, t.PrefixPrompt, llmInput, t.NoCache)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if exactReused+nearReused > 0 {
+		var missing []string
+		translation, missing = mergeTranslation(lines, translation)
+		for _, line := range missing {
+			slog.Warn("LLM output has no translation for line", "text", truncateText(line, 60))
+		}
+	}
+
+	if t.Bible != nil {
+		var issues []scriptureIssue
+		translation, issues = substituteScripture(translation, t.Bible)
+		for _, issue := range issues {
+			slog.Warn("unable to substitute scripture", "pair", issue.Pair, "reference", issue.Reference, "reason", issue.Reason)
+		}
+	} else {
+		slog.Info("no Bible data file, keeping the LLM's scripture translations")
+	}
+	return translation, lines, nil
+}
+
+// translateWithCache translates input with Grok, reusing a cached translation of the same
+// prompts, model and input unless noCache is set
+func translateWithCache(ctx context.Context, auth authSettings, systemPrompt, prefixPrompt, input string, noCache bool) (string, error) {
+	req := grokRequest{
+		Input: []grokMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: prefixPrompt + "\n" + input},
+		},
+		Model: grokModel,
+	}
+	slog.Info("STEP 3 OK: built Grok request payload")
+
+	cache := newTranslationCache()
+	cacheKey := translationCacheKey(systemPrompt, prefixPrompt, grokModel, input)
+	if !noCache {
+		cached, err := cache.Get(cacheKey)
+		if err != nil {
+			slog.Warn("unable to read translation cache", "err", err)
+		} else if cached != nil {
+			slog.Info("using cached translation", "key", cacheKey[:16], "cached_at", cached.CreatedAt)
+			return cached.Translation, nil
+		}
+	}
+
+	grokKey, err := auth.xaiKey()
+	if err != nil {
+		return "", fmt.Errorf("unable to read xAI API key: %v", err)
+	}
+	translation, err := callGrokResponses(ctx, grokKey, req)
+	if err != nil {
+		return "", err
+	}
+	if translation == "" {
+		return "", fmt.Errorf("grok returned empty translation")
+	}
+	entry := &cachedTranslation{Key: cacheKey, Model: grokModel, CreatedAt: time.Now(), InputChars: len([]rune(input)), Translation: translation}
+	if err := cache.Put(entry); err != nil {
+		slog.Warn("unable to cache translation", "err", err)
+	}
+	return translation, nil
+}
+
 func callGrokResponses(ctx context.Context, apiKey string, req grokRequest) (string, error) {
 	b, err := json.Marshal(req)
 	if err != nil {
@@ -429,11 +691,33 @@ func extractTextFromXAIResponse(body []byte) (string, error) {
 	return "", errors.New("no recognizable text field found")
 }
 
-func waitForUserReview(outputURL string) bool {
+// waitForUserReview asks the reviewer on in to confirm the output doc. With a positive timeout
+// it gives up when nobody answers in time; end of input counts as an abort.
+func waitForUserReview(in io.Reader, outputURL string, timeout time.Duration) reviewOutcome {
 	fmt.Printf("Review output doc: %s\n", outputURL)
 	fmt.Print("Press Y then Enter to continue, anything else to abort: ")
-	reader := bufio.NewReader(os.Stdin)
-	line, _ := reader.ReadString('\n')
-	line = strings.TrimSpace(line)
-	return strings.EqualFold(line, "y")
+
+	answers := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(in).ReadString('\n')
+		answers <- strings.TrimSpace(line)
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case line := <-answers:
+		if strings.EqualFold(line, "y") {
+			return reviewApproved
+		}
+		return reviewAborted
+	case <-expired:
+		fmt.Println()
+		return reviewTimedOut
+	}
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForUserReview(t *testing.T) {
+	blocked, writer := io.Pipe()
+	defer writer.Close()
+
+	tests := []struct {
+		name     string
+		in       io.Reader
+		timeout  time.Duration
+		expected reviewOutcome
+	}{
+		{"Confirmed", strings.NewReader("y\n"), 0, reviewApproved},
+		{"Confirmed with timeout", strings.NewReader(" Y \n"), time.Second, reviewApproved},
+		{"Declined", strings.NewReader("n\n"), 0, reviewAborted},
+		{"No input", strings.NewReader(""), 0, reviewAborted},
+		{"Nobody answers", blocked, 10 * time.Millisecond, reviewTimedOut},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := waitForUserReview(tt.in, "https://docs.google.com/document/d/x/edit", tt.timeout)
+			if result != tt.expected {
+				t.Errorf("waitForUserReview() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateE2EOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    e2eOptions
+		mode    string
+		wantErr bool
+	}{
+		{"Defaults", e2eOptions{}, "", false},
+		{"Skip review", e2eOptions{Yes: true}, "copy", false},
+		{"Timed review", e2eOptions{ReviewTimeout: time.Minute}, "", false},
+		{"Yes and stop after write", e2eOptions{Yes: true, StopAfterWrite: true}, "", true},
+		{"Yes and timeout", e2eOptions{Yes: true, ReviewTimeout: time.Minute}, "", true},
+		{"Stop after write and timeout", e2eOptions{StopAfterWrite: true, ReviewTimeout: time.Minute}, "", true},
+		{"Negative timeout", e2eOptions{ReviewTimeout: -time.Minute}, "", true},
+		{"Mode on resume", e2eOptions{ResumeRunID: "20240310-093000-1a2b3c"}, "table", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateE2EOptions(tt.opts, tt.mode); (err != nil) != tt.wantErr {
+				t.Errorf("validateE2EOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
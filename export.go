@@ -0,0 +1,443 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/drive/v3"
+)
+
+// Export formats
+const (
+	ExportDOCX     = "docx"
+	ExportPDF      = "pdf"
+	ExportMarkdown = "md"
+	ExportHTML     = "html"
+	ExportText     = "txt"
+)
+
+// Export languages
+const (
+	ExportBoth    = "both"
+	ExportEnglish = "en"
+	ExportChinese = "zh"
+)
+
+// driveExportMimeTypes maps the formats exported by Drive to their MIME types
+var driveExportMimeTypes = map[string]string{
+	ExportDOCX: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	ExportPDF:  "application/pdf",
+}
+
+// exportLine is a line of a bilingual doc with the formatting the native renderers use
+type exportLine struct {
+	Text     string
+	Features *LineFeatures
+	Heading  int  // 1-6 for HEADING_n paragraphs, 0 otherwise
+	Chinese  bool // a translation line
+}
+
+// validateExportOptions checks the format and language and returns them normalized
+func validateExportOptions(format, lang string) (string, string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		lang = ExportBoth
+	}
+	switch format {
+	case ExportDOCX, ExportPDF, ExportMarkdown, ExportHTML, ExportText:
+	default:
+		return "", "", fmt.Errorf("unknown export format %q (want docx, pdf, md, html or txt)", format)
+	}
+	switch lang {
+	case ExportBoth, ExportEnglish, ExportChinese:
+	default:
+		return "", "", fmt.Errorf("unknown export language %q (want both, en or zh)", lang)
+	}
+	return format, lang, nil
+}
+
+// headingLevel returns the level of a HEADING_n named style, or 0
+func headingLevel(element *docs.StructuralElement) int {
+	if element.Paragraph == nil || element.Paragraph.ParagraphStyle == nil {
+		return 0
+	}
+	var level int
+	if _, err := fmt.Sscanf(element.Paragraph.ParagraphStyle.NamedStyleType, "HEADING_%d", &level); err != nil {
+		return 0
+	}
+	return level
+}
+
+// collectExportLines reads every non-empty line of a document with its formatting
+func collectExportLines(doc *docs.Document) ([]exportLine, error) {
+	cursor := &DocumentCursor{Document: doc, ElementIndex: 0, LineIndex: 0}
+	var lines []exportLine
+	for {
+		lineInfo, err := getNextNonEmptyLine(cursor)
+		if err != nil {
+			if err.Error() == "end of document" {
+				break
+			}
+			return nil, err
+		}
+		lines = append(lines, exportLine{
+			Text:     lineInfo.Text,
+			Features: extractLineFeatures(lineInfo.Element, lineInfo.TextRun, lineInfo.Text),
+			Heading:  headingLevel(lineInfo.Element),
+			Chinese:  isTranslationLine(classifyLineType(lineInfo.Text)),
+		})
+	}
+	return lines, nil
+}
+
+// keepForLanguage reports whether a line belongs in the export of lang; lines without English, such
+// as separators, are kept in the Chinese export too
+func keepForLanguage(text string, chinese bool, lang string) bool {
+	switch lang {
+	case ExportChinese:
+		return chinese || generateLineKey(text) == ""
+	case ExportEnglish:
+		return !chinese
+	default:
+		return true
+	}
+}
+
+// filterExportLines keeps the lines of one language
+func filterExportLines(lines []exportLine, lang string) []exportLine {
+	if lang == ExportBoth {
+		return lines
+	}
+	var filtered []exportLine
+	for _, line := range lines {
+		if keepForLanguage(line.Text, line.Chinese, lang) {
+			filtered = append(filtered, line)
+		}
+	}
+	return filtered
+}
+
+// languageDeleteRequests returns the requests that delete the body paragraphs of a doc that the
+// export of lang leaves out, last first so earlier indexes stay valid. Tables are kept whole.
+func languageDeleteRequests(doc *docs.Document, lang string) []*docs.Request {
+	if lang == ExportBoth || doc.Body == nil || len(doc.Body.Content) == 0 {
+		return nil
+	}
+	content := doc.Body.Content
+
+	// Runs of consecutive paragraphs to delete, with the content index of their first paragraph
+	type run struct {
+		first      int
+		start, end int64
+	}
+	var runs []run
+	open := false
+	for i, element := range content {
+		if element.Paragraph == nil {
+			open = false
+			continue
+		}
+		text := paragraphText(element)
+		if keepForLanguage(text, isTranslationLine(classifyLineType(text)), lang) {
+			open = false
+			continue
+		}
+		if open {
+			runs[len(runs)-1].end = element.EndIndex
+		} else {
+			runs = append(runs, run{first: i, start: element.StartIndex, end: element.EndIndex})
+			open = true
+		}
+	}
+
+	bodyEnd := content[len(content)-1].EndIndex
+	var requests []*docs.Request
+	for i := len(runs) - 1; i >= 0; i-- {
+		r := runs[i]
+		// The body's last newline can't be deleted, so a run at the end takes the newline of the
+		// paragraph before it instead; after a table an empty paragraph is left
+		if r.end == bodyEnd {
+			r.end--
+			if r.first > 0 && content[r.first-1].Paragraph != nil {
+				r.start--
+			}
+		}
+		if r.start < r.end {
+			requests = append(requests, &docs.Request{DeleteContentRange: &docs.DeleteContentRangeRequest{
+				Range: &docs.Range{StartIndex: r.start, EndIndex: r.end},
+			}})
+		}
+	}
+	return requests
+}
+
+var (
+	markdownSpecial     = regexp.MustCompile("[\\\\`*_\\[\\]<>]")
+	markdownBlockStart  = regexp.MustCompile(`^(#|[-+]\s)`)
+	markdownOrderedItem = regexp.MustCompile(`^(\d+)([.)]\s)`)
+)
+
+// escapeMarkdown keeps literal text from being read as Markdown syntax
+func escapeMarkdown(text string) string {
+	text = markdownSpecial.ReplaceAllString(text, `\$0`)
+	if markdownBlockStart.MatchString(text) {
+		return `\` + text
+	}
+	return markdownOrderedItem.ReplaceAllString(text, `$1\$2`)
+}
+
+// renderMarkdown renders the lines as Markdown: headings, nested bullets and bold/italic text
+func renderMarkdown(title string, lines []exportLine) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n", escapeMarkdown(title))
+	inList := false
+	for _, line := range lines {
+		text := escapeMarkdown(line.Text)
+		f := line.Features
+		if line.Heading == 0 {
+			if f.Bold && f.Italic {
+				text = "***" + text + "***"
+			} else if f.Bold {
+				text = "**" + text + "**"
+			} else if f.Italic {
+				text = "*" + text + "*"
+			}
+		}
+
+		switch {
+		case f.HasBullet:
+			if !inList {
+				sb.WriteString("\n")
+			}
+			fmt.Fprintf(&sb, "%s- %s\n", strings.Repeat("  ", int(f.NestingLevel)), text)
+			inList = true
+			continue
+		case line.Heading > 0:
+			// The document title is the only level-1 heading
+			fmt.Fprintf(&sb, "\n%s %s\n", strings.Repeat("#", min(line.Heading+1, 6)), text)
+		default:
+			fmt.Fprintf(&sb, "\n%s%s\n", strings.Repeat("&emsp;", f.LeadingTabs), text)
+		}
+		inList = false
+	}
+	return sb.String()
+}
+
+// htmlLineStyle renders the formatting of a line as an inline CSS style
+func htmlLineStyle(f *LineFeatures) string {
+	var styles []string
+	switch f.Alignment {
+	case "CENTER":
+		styles = append(styles, "text-align: center")
+	case "END":
+		styles = append(styles, "text-align: right")
+	case "JUSTIFIED":
+		styles = append(styles, "text-align: justify")
+	}
+	if f.Bold {
+		styles = append(styles, "font-weight: bold")
+	}
+	if f.Italic {
+		styles = append(styles, "font-style: italic")
+	}
+	if f.Underline {
+		styles = append(styles, "text-decoration: underline")
+	}
+	if f.FontSize != nil {
+		styles = append(styles, fmt.Sprintf("font-size: %.1fpt", *f.FontSize))
+	}
+	if f.TextColor != nil {
+		styles = append(styles, fmt.Sprintf("color: rgb(%.0f, %.0f, %.0f)", f.TextColor.Red, f.TextColor.Green, f.TextColor.Blue))
+	}
+	indent := float64(f.LeadingTabs) * 36
+	if f.LeftIndent != nil {
+		indent += *f.LeftIndent
+	}
+	if indent > 0 && !f.HasBullet {
+		styles = append(styles, fmt.Sprintf("margin-left: %.1fpt", indent))
+	}
+	return strings.Join(styles, "; ")
+}
+
+// renderHTML renders the lines as a self-contained HTML page
+func renderHTML(title string, lines []exportLine) string {
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>%s</title>\n", html.EscapeString(title))
+	sb.WriteString("<style>\n" +
+		"body { font-family: Georgia, \"Songti SC\", \"SimSun\", serif; max-width: 48em; margin: 2em auto; line-height: 1.5; }\n" +
+		"p { margin: 0.2em 0; }\n" +
+		".zh { font-family: \"PingFang SC\", \"Microsoft YaHei\", sans-serif; }\n" +
+		"</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n", html.EscapeString(title))
+
+	depth := 0 // open <ul> elements
+	for _, line := range lines {
+		f := line.Features
+		class := "en"
+		if line.Chinese {
+			class = "zh"
+		}
+		attrs := fmt.Sprintf(" class=\"%s\"", class)
+		if style := htmlLineStyle(f); style != "" {
+			attrs += fmt.Sprintf(" style=\"%s\"", style)
+		}
+		text := html.EscapeString(line.Text)
+
+		want := 0
+		if f.HasBullet {
+			want = int(f.NestingLevel) + 1
+		}
+		for ; depth < want; depth++ {
+			sb.WriteString("<ul>\n")
+		}
+		for ; depth > want; depth-- {
+			sb.WriteString("</ul>\n")
+		}
+
+		switch {
+		case f.HasBullet:
+			fmt.Fprintf(&sb, "<li%s>%s</li>\n", attrs, text)
+		case line.Heading > 0:
+			tag := fmt.Sprintf("h%d", min(line.Heading+1, 6))
+			fmt.Fprintf(&sb, "<%s%s>%s</%s>\n", tag, attrs, text, tag)
+		default:
+			fmt.Fprintf(&sb, "<p%s>%s</p>\n", attrs, text)
+		}
+	}
+	for ; depth > 0; depth-- {
+		sb.WriteString("</ul>\n")
+	}
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
+
+// renderPlainText renders the lines one per line, keeping their leading tabs
+func renderPlainText(lines []exportLine) string {
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(strings.Repeat("\t", line.Features.LeadingTabs))
+		sb.WriteString(line.Text)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// exportDriveFile downloads a Google Doc converted by Drive to mimeType
+func exportDriveFile(ctx context.Context, srv *drive.Service, fileID, mimeType string) ([]byte, error) {
+	var data []byte
+	err := defaultRetryPolicy.do(ctx, "files.export", func() error {
+		resp, err := srv.Files.Export(fileID, mimeType).Context(ctx).Download()
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		data, err = io.ReadAll(resp.Body)
+		return err
+	})
+	return data, err
+}
+
+// exportLanguageCopy exports one language of a doc through Drive. The other language is deleted
+// from a copy of the doc, which goes to the trash afterwards.
+func exportLanguageCopy(ctx context.Context, c *clients, srv *drive.Service, doc *docs.Document, lang, mimeType string) ([]byte, error) {
+	copied, err := copyInputDoc(ctx, srv, doc.DocumentId, doc.Title+" ("+lang+" export)", "")
+	if err != nil {
+		return nil, err
+	}
+	defer trashTemporaryDoc(ctx, c, copied.Id)
+	if err := grantInputAccess(c, srv, copied.Id); err != nil {
+		return nil, fmt.Errorf("unable to grant access to the copy: %v", err)
+	}
+
+	copyDoc, err := getDocument(ctx, c.Docs, copied.Id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the copy: %v", err)
+	}
+	if requests := languageDeleteRequests(copyDoc, lang); len(requests) > 0 {
+		req := &docs.BatchUpdateDocumentRequest{Requests: requests}
+		if _, err := batchUpdateDocument(ctx, c.Docs, copied.Id, req); err != nil {
+			return nil, fmt.Errorf("unable to remove the other language from the copy: %v", err)
+		}
+		slog.Info("removed other language from export copy", "lang", lang, "ranges", len(requests))
+	}
+	return exportDriveFile(ctx, srv, copied.Id, mimeType)
+}
+
+// exportFileName derives an output file name from the document title
+func exportFileName(title, format, lang string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		name = "export"
+	}
+	if lang != ExportBoth {
+		name += "." + lang
+	}
+	return name + "." + format
+}
+
+// exportDocument writes a bilingual doc to an offline file
+func exportDocument(c *clients, docURL, format, lang, outPath string) {
+	ctx := context.Background()
+	docID := extractDocumentID(docURL)
+	if docID == "" {
+		logFatal("could not extract document ID from URL", "url", docURL)
+	}
+
+	doc, err := getDocument(ctx, c.Docs, docID)
+	if err != nil {
+		logFatal("unable to retrieve document", "err", err)
+	}
+	if outPath == "" {
+		outPath = exportFileName(doc.Title, format, lang)
+	}
+
+	var data []byte
+	if mimeType, ok := driveExportMimeTypes[format]; ok {
+		srv, err := c.UserDrive(ctx)
+		if err != nil {
+			logFatal("unable to create Drive service", "err", err)
+		}
+		if lang == ExportBoth {
+			data, err = exportDriveFile(ctx, srv, docID, mimeType)
+		} else {
+			data, err = exportLanguageCopy(ctx, c, srv, doc, lang, mimeType)
+		}
+		if err != nil {
+			logFatal("unable to export document from Drive", "format", format, "err", err)
+		}
+	} else {
+		lines, err := collectExportLines(doc)
+		if err != nil {
+			logFatal("unable to read document lines", "err", err)
+		}
+		lines = filterExportLines(lines, lang)
+		switch format {
+		case ExportMarkdown:
+			data = []byte(renderMarkdown(doc.Title, lines))
+		case ExportHTML:
+			data = []byte(renderHTML(doc.Title, lines))
+		default:
+			data = []byte(renderPlainText(lines))
+		}
+		slog.Info("rendered document", "lines", len(lines), "lang", lang)
+	}
+
+	if err := os.WriteFile(outPath, data, 0644); err != nil {
+		logFatal("unable to write export file", "path", outPath, "err", err)
+	}
+	fmt.Printf("Exported %q to %s (%d bytes)\n", doc.Title, outPath, len(data))
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateExportOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		lang    string
+		wantErr bool
+	}{
+		{"Markdown, Chinese only", "MD", "zh", false},
+		{"HTML, default language", "html", "", false},
+		{"PDF, both languages", "pdf", "both", false},
+		{"DOCX, English only", "docx", "en", false},
+		{"Unknown format", "odt", "both", true},
+		{"Unknown language", "txt", "fr", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := validateExportOptions(tt.format, tt.lang)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateExportOptions(%q, %q) error = %v, wantErr %v", tt.format, tt.lang, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLanguageDeleteRequests(t *testing.T) {
+	texts := []string{"Sermon Outline", "讲道大纲", "——", "Amen", "阿们", "Go in peace", "平安地去吧"}
+	text := ""
+	for _, line := range texts {
+		text += line + "\n"
+	}
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{ExportEnglish, "Sermon Outline\n——\nAmen\nGo in peace\n"},
+		{ExportChinese, "讲道大纲\n——\n阿们\n平安地去吧\n"},
+		{ExportBoth, text},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			requests := languageDeleteRequests(buildParagraphDocument(texts...), tt.lang)
+			if got := applyRequests(text, requests); got != tt.want {
+				t.Errorf("export copy = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The body's final newline stays even when every paragraph goes
+	requests := languageDeleteRequests(buildParagraphDocument("阿们", "平安地去吧"), ExportEnglish)
+	if got := applyRequests("阿们\n平安地去吧\n", requests); got != "\n" {
+		t.Errorf("export copy = %q, want a single empty paragraph", got)
+	}
+}
+
+func testExportLines() []exportLine {
+	return []exportLine{
+		{Text: "Sermon Outline", Features: &LineFeatures{Alignment: "CENTER", Bold: true}, Heading: 1},
+		{Text: "讲道大纲", Features: &LineFeatures{Alignment: "CENTER", Bold: true}, Heading: 1, Chinese: true},
+		{Text: "God's love *never* fails", Features: &LineFeatures{Italic: true}},
+		{Text: "神的爱永不失败", Features: &LineFeatures{Italic: true}, Chinese: true},
+		{Text: "——", Features: &LineFeatures{}},
+		{Text: "First point", Features: &LineFeatures{HasBullet: true}},
+		{Text: "第一点", Features: &LineFeatures{HasBullet: true}, Chinese: true},
+		{Text: "Sub point", Features: &LineFeatures{HasBullet: true, NestingLevel: 1}},
+		{Text: "Closing", Features: &LineFeatures{LeadingTabs: 1}},
+	}
+}
+
+func TestFilterExportLines(t *testing.T) {
+	tests := []struct {
+		lang     string
+		expected []string
+	}{
+		{ExportBoth, []string{"Sermon Outline", "讲道大纲", "God's love *never* fails", "神的爱永不失败", "——", "First point", "第一点", "Sub point", "Closing"}},
+		{ExportEnglish, []string{"Sermon Outline", "God's love *never* fails", "——", "First point", "Sub point", "Closing"}},
+		{ExportChinese, []string{"讲道大纲", "神的爱永不失败", "——", "第一点"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			var got []string
+			for _, line := range filterExportLines(testExportLines(), tt.lang) {
+				got = append(got, line.Text)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("filterExportLines(%s) = %v, want %v", tt.lang, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	md := renderMarkdown("Week 10", testExportLines())
+	expected := "# Week 10\n" +
+		"\n## Sermon Outline\n" +
+		"\n## 讲道大纲\n" +
+		"\n*God's love \\*never\\* fails*\n" +
+		"\n*神的爱永不失败*\n" +
+		"\n——\n" +
+		"\n- First point\n" +
+		"- 第一点\n" +
+		"  - Sub point\n" +
+		"\n&emsp;Closing\n"
+	if md != expected {
+		t.Errorf("renderMarkdown() =\n%s\nwant\n%s", md, expected)
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	page := renderHTML("Week <10>", testExportLines())
+	for _, want := range []string{
+		"<title>Week &lt;10&gt;</title>",
+		`<h2 class="en" style="text-align: center; font-weight: bold">Sermon Outline</h2>`,
+		`<p class="zh" style="font-style: italic">神的爱永不失败</p>`,
+		"<ul>\n<li class=\"en\">First point</li>\n<li class=\"zh\">第一点</li>\n<ul>\n<li class=\"en\">Sub point</li>\n</ul>\n</ul>\n",
+		`<p class="en" style="margin-left: 36.0pt">Closing</p>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("renderHTML() is missing %q", want)
+		}
+	}
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"#1 priority", `\#1 priority`},
+		{"- not a list", `\- not a list`},
+		{"3. not a list", `3\. not a list`},
+		{"a_b [c]", `a\_b \[c\]`},
+	}
+	for _, tt := range tests {
+		if got := escapeMarkdown(tt.text); got != tt.expected {
+			t.Errorf("escapeMarkdown(%q) = %q, want %q", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestExportFileName(t *testing.T) {
+	if got := exportFileName("Sermon 3/10: Grace", ExportPDF, ExportBoth); got != "Sermon 3_10_ Grace.pdf" {
+		t.Errorf("exportFileName() = %q", got)
+	}
+	if got := exportFileName(" ", ExportMarkdown, ExportChinese); got != "export.zh.md" {
+		t.Errorf("exportFileName() = %q", got)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultGlossaryFile is read by e2e when application.yaml doesn't name a glossary
+const defaultGlossaryFile = "glossary.yaml"
+
+// glossaryEntry is an English term and the Chinese rendering every translation must use
+type glossaryEntry struct {
+	English string `yaml:"en"`
+	Chinese string `yaml:"zh"`
+	Notes   string `yaml:"notes"`
+}
+
+// glossaryFile is the contents of the glossary file
+type glossaryFile struct {
+	Terms []glossaryEntry `yaml:"terms"`
+}
+
+// glossaryViolation is a translated line pair whose English contains a glossary term
+// but whose Chinese lacks the required rendering
+type glossaryViolation struct {
+	Pair int // 1-based index into the translation's line pairs
+	Term glossaryEntry
+	Line translationPair
+}
+
+// loadGlossary reads a glossary file; a missing file yields an empty glossary
+func loadGlossary(path string) ([]glossaryEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var file glossaryFile
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("unable to parse glossary %s: %v", path, err)
+	}
+	for i, term := range file.Terms {
+		term.English = strings.TrimSpace(term.English)
+		term.Chinese = strings.TrimSpace(term.Chinese)
+		if term.English == "" || term.Chinese == "" {
+			return nil, fmt.Errorf("glossary %s: term %d needs both en and zh", path, i+1)
+		}
+		file.Terms[i] = term
+	}
+	return file.Terms, nil
+}
+
+// glossaryPrompt renders the glossary as instructions appended to the system prompt
+func glossaryPrompt(terms []glossaryEntry) string {
+	if len(terms) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\nAlways translate the following terms exactly as given:\n")
+	for _, term := range terms {
+		fmt.Fprintf(&sb, "- %s → %s", term.English, term.Chinese)
+		if term.Notes != "" {
+			fmt.Fprintf(&sb, " (%s)", term.Notes)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// glossaryTermPattern matches a term as whole words, ignoring case and runs of whitespace
+func glossaryTermPattern(term string) *regexp.Regexp {
+	words := strings.Fields(term)
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	pattern := strings.Join(words, `\s+`)
+	// \b only applies next to word characters, so terms like "St." still match
+	if isWordByte(term[0]) {
+		pattern = `\b` + pattern
+	}
+	if isWordByte(term[len(term)-1]) {
+		pattern += `\b`
+	}
+	return regexp.MustCompile(`(?i)` + pattern)
+}
+
+// isWordByte reports whether b is an ASCII word character in the regexp sense
+func isWordByte(b byte) bool {
+	return b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+// checkGlossary reports every line pair that uses a glossary term in English without
+// the term's Chinese rendering
+func checkGlossary(pairs []translationPair, terms []glossaryEntry) []glossaryViolation {
+	patterns := make([]*regexp.Regexp, len(terms))
+	for i, term := range terms {
+		patterns[i] = glossaryTermPattern(term.English)
+	}
+
+	var violations []glossaryViolation
+	for i, pair := range pairs {
+		for j, term := range terms {
+			if patterns[j].MatchString(pair.English) && !strings.Contains(pair.Chinese, term.Chinese) {
+				violations = append(violations, glossaryViolation{Pair: i + 1, Term: term, Line: pair})
+			}
+		}
+	}
+	return violations
+}
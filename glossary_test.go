@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadGlossary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "glossary.yaml")
+	content := "terms:\n  - en: Holy Spirit\n    zh: 圣灵\n    notes: never 圣神\n  - en: ' Pastor John '\n    zh: 约翰牧师\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	terms, err := loadGlossary(path)
+	if err != nil {
+		t.Fatalf("loadGlossary() error = %v", err)
+	}
+	if len(terms) != 2 || terms[0].Notes != "never 圣神" || terms[1].English != "Pastor John" {
+		t.Errorf("loadGlossary() = %+v", terms)
+	}
+
+	if terms, err := loadGlossary(filepath.Join(dir, "missing.yaml")); err != nil || terms != nil {
+		t.Errorf("loadGlossary() of a missing file = %+v, %v", terms, err)
+	}
+
+	if err := os.WriteFile(path, []byte("terms:\n  - en: Grace\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadGlossary(path); err == nil {
+		t.Error("loadGlossary() should reject a term without zh")
+	}
+}
+
+func TestGlossaryPrompt(t *testing.T) {
+	if glossaryPrompt(nil) != "" {
+		t.Error("glossaryPrompt() of an empty glossary should be empty")
+	}
+	prompt := glossaryPrompt([]glossaryEntry{{English: "Holy Spirit", Chinese: "圣灵", Notes: "never 圣神"}})
+	if !strings.Contains(prompt, "- Holy Spirit → 圣灵 (never 圣神)") {
+		t.Errorf("glossaryPrompt() = %q", prompt)
+	}
+}
+
+func TestCheckGlossary(t *testing.T) {
+	terms := []glossaryEntry{
+		{English: "Holy Spirit", Chinese: "圣灵"},
+		{English: "Pastor John", Chinese: "约翰牧师"},
+		{English: "St. Paul", Chinese: "圣保罗"},
+	}
+
+	tests := []struct {
+		name     string
+		pair     translationPair
+		expected []string
+	}{
+		{"Rendering present", translationPair{"The Holy Spirit guides us.", "圣灵引导我们。"}, nil},
+		{"Rendering missing", translationPair{"The holy  spirit guides us.", "圣神引导我们。"}, []string{"Holy Spirit"}},
+		{"Two terms", translationPair{"Pastor John on the Holy Spirit", "约翰牧师谈圣神"}, []string{"Holy Spirit"}},
+		{"Partial word", translationPair{"Pastor Johnson preaches", "约翰逊牧师讲道"}, nil},
+		{"Term ending in punctuation", translationPair{"Letters of St. Paul", "保罗书信"}, []string{"St. Paul"}},
+		{"No term", translationPair{"Welcome", "欢迎"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := checkGlossary([]translationPair{tt.pair}, terms)
+			var got []string
+			for _, v := range violations {
+				got = append(got, v.Term.English)
+				if v.Pair != 1 {
+					t.Errorf("violation pair = %d, want 1", v.Pair)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("checkGlossary() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
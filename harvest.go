@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// harvestResult counts what a harvest did to the translation memory
+type harvestResult struct {
+	Added     int
+	Updated   int
+	Unchanged int
+}
+
+// harvestPairs walks a bilingual document line by line and pairs each English line with the
+// Chinese lines that follow it. Chinese lines with no English before them are skipped.
+func harvestPairs(doc *docs.Document) ([]translationPair, error) {
+	cursor := &DocumentCursor{Document: doc, ElementIndex: 0, LineIndex: 0}
+	var pairs []translationPair
+	var current *translationPair
+	for {
+		lineInfo, err := getNextNonEmptyLine(cursor)
+		if err != nil {
+			if err.Error() == "end of document" {
+				break
+			}
+			return nil, err
+		}
+
+		text := lineInfo.Text
+		lineType := classifyLineType(text)
+		switch {
+		case isTranslationLine(lineType):
+			if current == nil {
+				continue
+			}
+			if current.Chinese == "" {
+				current.Chinese = text
+			} else {
+				current.Chinese += "\n" + text
+			}
+		case generateLineKey(text) != "":
+			pairs = append(pairs, translationPair{English: text})
+			current = &pairs[len(pairs)-1]
+		default:
+			// Separators and other lines without English end the current pair
+			current = nil
+		}
+	}
+
+	// Keep only the English lines that received a translation
+	translated := pairs[:0]
+	for _, pair := range pairs {
+		if pair.Chinese != "" {
+			translated = append(translated, pair)
+		}
+	}
+	return translated, nil
+}
+
+// harvestIntoMemory upserts line pairs from a document into the translation memory
+func harvestIntoMemory(tm *translationMemory, pairs []translationPair, docID string, now time.Time) harvestResult {
+	var result harvestResult
+	for _, pair := range pairs {
+		_, existed := tm.Entries[generateLineKey(pair.English)]
+		switch {
+		case !tm.upsert(pair.English, pair.Chinese, docID, now):
+			result.Unchanged++
+		case existed:
+			result.Updated++
+		default:
+			result.Added++
+		}
+	}
+	return result
+}
+
+// harvestDocuments reads finished bilingual docs and stores their line pairs in the translation memory
+func harvestDocuments(c *clients, memoryPath string, docURLs []string) {
+	ctx := context.Background()
+	tm, err := loadTranslationMemory(memoryPath)
+	if err != nil {
+		logFatal("failed to read translation memory", "err", err)
+	}
+
+	var total harvestResult
+	for _, docURL := range docURLs {
+		docID := extractDocumentID(docURL)
+		if docID == "" {
+			logFatal("could not extract document ID from URL", "url", docURL)
+		}
+		doc, err := getDocument(ctx, c.Docs, docID)
+		if err != nil {
+			logFatal("unable to retrieve document", "doc_id", docID, "err", err)
+		}
+		pairs, err := harvestPairs(doc)
+		if err != nil {
+			logFatal("unable to read document lines", "doc_id", docID, "err", err)
+		}
+		result := harvestIntoMemory(tm, pairs, docID, time.Now())
+		slog.Info("harvested document", "title", doc.Title, "pairs", len(pairs),
+			"added", result.Added, "updated", result.Updated, "unchanged", result.Unchanged)
+		total.Added += result.Added
+		total.Updated += result.Updated
+		total.Unchanged += result.Unchanged
+	}
+
+	if err := saveTranslationMemory(memoryPath, tm); err != nil {
+		logFatal("failed to save translation memory", "err", err)
+	}
+	fmt.Printf("Translation memory %s: %d added, %d updated, %d unchanged, %d entries in total\n",
+		memoryPath, total.Added, total.Updated, total.Unchanged, len(tm.Entries))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHarvestPairs(t *testing.T) {
+	doc := buildParagraphDocument("宣召之前", "Call to Worship", "宣召", "——", "孤立的中文", "Sermon", "讲道", "第二行", "No translation", "Benediction", "祝福")
+
+	pairs, err := harvestPairs(doc)
+	if err != nil {
+		t.Fatalf("harvestPairs() error = %v", err)
+	}
+	expected := []translationPair{
+		{English: "Call to Worship", Chinese: "宣召"},
+		{English: "Sermon", Chinese: "讲道\n第二行"},
+		{English: "Benediction", Chinese: "祝福"},
+	}
+	if len(pairs) != len(expected) {
+		t.Fatalf("harvestPairs() = %+v, want %+v", pairs, expected)
+	}
+	for i := range expected {
+		if pairs[i] != expected[i] {
+			t.Errorf("pair %d = %+v, want %+v", i, pairs[i], expected[i])
+		}
+	}
+}
+
+func TestHarvestIntoMemory(t *testing.T) {
+	tm := &translationMemory{Entries: make(map[string]*tmEntry)}
+	now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	tm.upsert("Benediction", "祝福", "old-doc", now.AddDate(0, 0, -7))
+	tm.upsert("Call to Worship", "宣召", "old-doc", now.AddDate(0, 0, -7))
+
+	pairs := []translationPair{
+		{English: "Call to Worship", Chinese: "宣召"},
+		{English: "Benediction", Chinese: "祝祷"},
+		{English: "Sermon", Chinese: "讲道"},
+	}
+	result := harvestIntoMemory(tm, pairs, "new-doc", now)
+	if result != (harvestResult{Added: 1, Updated: 1, Unchanged: 1}) {
+		t.Errorf("harvestIntoMemory() = %+v", result)
+	}
+
+	entry := tm.Entries[generateLineKey("Benediction")]
+	if entry.Chinese != "祝祷" || entry.SourceDocID != "new-doc" || !entry.UpdatedAt.Equal(now) {
+		t.Errorf("corrected entry = %+v", entry)
+	}
+	if tm.Entries[generateLineKey("Call to Worship")].SourceDocID != "old-doc" {
+		t.Error("an unchanged entry should keep its original source doc")
+	}
+}
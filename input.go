@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// docxMimeType is the MIME type of Word documents, which Drive converts to Google Docs on upload
+const docxMimeType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// importLine is a paragraph of a local input file and the formatting it carries into the imported doc
+type importLine struct {
+	Text    string
+	Heading int // 1-6 for Markdown headings, 0 otherwise
+	Bullet  bool
+	Ordered bool // a numbered list item
+	Nesting int
+	Bold    bool
+	Italic  bool
+}
+
+// isLocalInput reports whether the configured input is a local file path rather than a Google Doc URL
+func isLocalInput(input string) bool {
+	lower := strings.ToLower(strings.TrimSpace(input))
+	return lower != "" && !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://")
+}
+
+var (
+	markdownEscape     = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()<>#+\\-.!|])")
+	markdownFence      = regexp.MustCompile("^\\s*(```|~~~)")
+	markdownRule       = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	markdownHeading    = regexp.MustCompile(`^(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+	markdownQuote      = regexp.MustCompile(`^\s*>\s?`)
+	markdownListItem   = regexp.MustCompile(`^([ \t]*)([-*+]|\d+[.)])\s+(.*)$`)
+	markdownImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink       = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownCode       = regexp.MustCompile("`([^`]*)`")
+	markdownStrong     = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	markdownEmphasis   = regexp.MustCompile(`\*(\S(?:[^*]*\S)?)\*`)
+	markdownUnderscore = regexp.MustCompile(`(^|[^\w])_(\S(?:[^_]*\S)?)_($|[^\w])`)
+)
+
+// escapedRuneBase maps a backslash-escaped ASCII character into the private use area so that it
+// isn't read as Markdown syntax while the line is parsed
+const escapedRuneBase = 0xE000
+
+// stripInlineMarkdown removes links, code spans and emphasis markers and reports whether the whole
+// line was bold or italic
+func stripInlineMarkdown(text string) (string, bool, bool) {
+	text = markdownEscape.ReplaceAllStringFunc(text, func(m string) string {
+		return string(rune(escapedRuneBase + int(m[1])))
+	})
+	text = markdownImage.ReplaceAllString(text, "$1")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = markdownCode.ReplaceAllString(text, "$1")
+
+	var bold, italic bool
+	if strings.HasPrefix(text, "***") && strings.HasSuffix(text, "***") && len(text) > 6 {
+		text, bold, italic = text[3:len(text)-3], true, true
+	}
+	if m := markdownStrong.FindStringSubmatch(text); m != nil && m[0] == text && m[1] == m[3] {
+		text, bold = m[2], true
+	}
+	if m := markdownEmphasis.FindStringSubmatch(text); m != nil && m[0] == text {
+		text, italic = m[1], true
+	} else if m := markdownUnderscore.FindStringSubmatch(text); m != nil && m[0] == text {
+		text, italic = m[2], true
+	}
+	text = markdownStrong.ReplaceAllString(text, "$2")
+	text = markdownEmphasis.ReplaceAllString(text, "$1")
+	text = markdownUnderscore.ReplaceAllString(text, "$1$2$3")
+
+	text = strings.Map(func(r rune) rune {
+		if r >= escapedRuneBase && r < escapedRuneBase+0x80 {
+			return r - escapedRuneBase
+		}
+		return r
+	}, text)
+	return html.UnescapeString(text), bold, italic
+}
+
+// parseMarkdownInput turns Markdown into one paragraph per non-blank line, keeping headings,
+// list items and whole-line emphasis. Blank lines only separate blocks and are dropped.
+func parseMarkdownInput(text string) []importLine {
+	var lines []importLine
+	inFence := false
+	for _, raw := range strings.Split(strings.ReplaceAll(strings.TrimPrefix(text, "\ufeff"), "\r\n", "\n"), "\n") {
+		if markdownFence.MatchString(raw) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			if strings.TrimSpace(raw) != "" {
+				lines = append(lines, importLine{Text: strings.TrimRight(raw, " \t")})
+			}
+			continue
+		}
+		raw = strings.TrimRight(raw, " \t")
+		if strings.TrimSpace(raw) == "" || markdownRule.MatchString(raw) {
+			continue
+		}
+		for markdownQuote.MatchString(raw) {
+			raw = markdownQuote.ReplaceAllString(raw, "")
+		}
+
+		var line importLine
+		if m := markdownHeading.FindStringSubmatch(raw); m != nil {
+			line.Heading = len(m[1])
+			raw = m[2]
+		} else if m := markdownListItem.FindStringSubmatch(raw); m != nil {
+			line.Bullet = true
+			line.Ordered = m[2] != "-" && m[2] != "*" && m[2] != "+"
+			width := len(strings.ReplaceAll(m[1], "\t", "  "))
+			line.Nesting = min(width/2, 8)
+			raw = m[3]
+		} else {
+			// The Markdown export writes leading tabs as em spaces
+			tabs := 0
+			for strings.HasPrefix(raw, "&emsp;") {
+				raw = strings.TrimPrefix(raw, "&emsp;")
+				tabs++
+			}
+			raw = strings.Repeat("\t", tabs) + strings.TrimLeft(raw, " ")
+		}
+		line.Text, line.Bold, line.Italic = stripInlineMarkdown(raw)
+		if strings.TrimSpace(line.Text) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parsePlainTextInput keeps every line of a text file as a paragraph, dropping trailing blank lines
+func parsePlainTextInput(text string) []importLine {
+	raw := strings.Split(strings.ReplaceAll(strings.TrimPrefix(text, "\ufeff"), "\r\n", "\n"), "\n")
+	for len(raw) > 0 && strings.TrimSpace(raw[len(raw)-1]) == "" {
+		raw = raw[:len(raw)-1]
+	}
+	lines := make([]importLine, len(raw))
+	for i, line := range raw {
+		lines[i] = importLine{Text: strings.TrimRight(line, " \t")}
+	}
+	return lines
+}
+
+// importRequests builds the batch that writes the lines into an empty doc and formats them.
+// Bullets come last, from the end of the doc backwards, because creating them removes the
+// leading tabs that set the nesting level.
+func importRequests(lines []importLine) []*docs.Request {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text
+		if line.Bullet {
+			texts[i] = strings.Repeat("\t", line.Nesting) + line.Text
+		}
+	}
+	reqs := []*docs.Request{{InsertText: &docs.InsertTextRequest{Location: &docs.Location{Index: 1}, Text: strings.Join(texts, "\n")}}}
+
+	starts := make([]int64, len(lines))
+	ends := make([]int64, len(lines))
+	pos := int64(1)
+	for i, text := range texts {
+		starts[i] = pos
+		ends[i] = pos + int64(len(utf16.Encode([]rune(text))))
+		pos = ends[i] + 1
+	}
+
+	for i, line := range lines {
+		if starts[i] == ends[i] {
+			continue
+		}
+		r := &docs.Range{StartIndex: starts[i], EndIndex: ends[i]}
+		if line.Heading > 0 {
+			reqs = append(reqs, &docs.Request{UpdateParagraphStyle: &docs.UpdateParagraphStyleRequest{
+				Range:          r,
+				ParagraphStyle: &docs.ParagraphStyle{NamedStyleType: fmt.Sprintf("HEADING_%d", line.Heading)},
+				Fields:         "namedStyleType",
+			}})
+		}
+		var fields []string
+		if line.Bold {
+			fields = append(fields, "bold")
+		}
+		if line.Italic {
+			fields = append(fields, "italic")
+		}
+		if len(fields) > 0 {
+			reqs = append(reqs, &docs.Request{UpdateTextStyle: &docs.UpdateTextStyleRequest{
+				Range:     r,
+				TextStyle: &docs.TextStyle{Bold: line.Bold, Italic: line.Italic},
+				Fields:    strings.Join(fields, ","),
+			}})
+		}
+	}
+
+	// Consecutive items of the same kind form one list
+	var lists []*docs.Request
+	for i := 0; i < len(lines); {
+		if !lines[i].Bullet {
+			i++
+			continue
+		}
+		j := i
+		for j+1 < len(lines) && lines[j+1].Bullet && lines[j+1].Ordered == lines[i].Ordered {
+			j++
+		}
+		preset := "BULLET_DISC_CIRCLE_SQUARE"
+		if lines[i].Ordered {
+			preset = "NUMBERED_DECIMAL_ALPHA_ROMAN"
+		}
+		lists = append(lists, &docs.Request{CreateParagraphBullets: &docs.CreateParagraphBulletsRequest{
+			Range:        &docs.Range{StartIndex: starts[i], EndIndex: max(ends[j], starts[j]+1)},
+			BulletPreset: preset,
+		}})
+		i = j + 1
+	}
+	for i := len(lists) - 1; i >= 0; i-- {
+		reqs = append(reqs, lists[i])
+	}
+	return reqs
+}
+
+// grantInputAccess lets the service account read an imported doc through the Docs API
+func grantInputAccess(c *clients, srv *drive.Service, fileID string) error {
+	if c.Auth.usesApplicationDefaultCredentials() {
+		return nil
+	}
+	email, err := c.Auth.serviceAccountEmail()
+	if err != nil {
+		return fmt.Errorf("unable to read service account email: %v", err)
+	}
+	return grantWriterToServiceAccount(srv, fileID, email)
+}
+
+// uploadConvertedDoc uploads a file to Drive, converting it to a Google Doc
+func uploadConvertedDoc(ctx context.Context, srv *drive.Service, name, folderID, mimeType string, data []byte) (*drive.File, error) {
+	f := &drive.File{Name: name, MimeType: googleDocMimeType}
+	if folderID != "" {
+		f.Parents = []string{folderID}
+	}
+	created, err := srv.Files.Create(f).Media(bytes.NewReader(data), googleapi.ContentType(mimeType)).
+		Fields("id", "name", "mimeType", "webViewLink").SupportsAllDrives(true).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to upload and convert %s: %w", name, err)
+	}
+	return created, nil
+}
+
+// importLocalInput turns a local .docx, .md or .txt file into a Google Doc owned by the OAuth user,
+// inside folderID when it is set, and returns the doc's URL. Word files are converted by Drive;
+// Markdown and text are parsed here and written with the Docs API.
+func importLocalInput(ctx context.Context, c *clients, path, folderID string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read input file: %v", err)
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	var lines []importLine
+	switch ext {
+	case ".docx":
+	case ".md", ".markdown":
+		lines = parseMarkdownInput(string(data))
+	case ".txt", ".text":
+		lines = parsePlainTextInput(string(data))
+	default:
+		return "", fmt.Errorf("unsupported input file type %q (want .docx, .md or .txt)", ext)
+	}
+	if ext != ".docx" && len(lines) == 0 {
+		return "", fmt.Errorf("input file %s has no text", path)
+	}
+
+	srv, err := c.UserDrive(ctx)
+	if err != nil {
+		return "", err
+	}
+	var created *drive.File
+	if ext == ".docx" {
+		created, err = uploadConvertedDoc(ctx, srv, name, folderID, docxMimeType, data)
+	} else {
+		created, err = createOutputDoc(ctx, srv, name, folderID)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := grantInputAccess(c, srv, created.Id); err != nil {
+		return "", fmt.Errorf("unable to grant service account access to the imported doc: %v", err)
+	}
+	if lines != nil {
+		req := &docs.BatchUpdateDocumentRequest{Requests: importRequests(lines)}
+		if _, err := batchUpdateDocument(ctx, c.Docs, created.Id, req); err != nil {
+			return "", fmt.Errorf("unable to write the imported doc: %v", err)
+		}
+	}
+	return fmt.Sprintf("https://docs.google.com/document/d/%s/edit", created.Id), nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsLocalInput(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"https://docs.google.com/document/d/abc123/edit", false},
+		{"HTTP://docs.google.com/document/d/abc123/edit", false},
+		{"sermons/2024-03-10.docx", true},
+		{"/home/pastor/outline.md", true},
+		{"  ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := isLocalInput(tt.input); got != tt.want {
+				t.Errorf("isLocalInput(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownInput(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []importLine
+	}{
+		{
+			name: "Headings, paragraphs and rules",
+			text: "# Sermon Outline #\n\nGod's love never fails\n\n---\n\n## Part 1\n",
+			want: []importLine{
+				{Text: "Sermon Outline", Heading: 1},
+				{Text: "God's love never fails"},
+				{Text: "Part 1", Heading: 2},
+			},
+		},
+		{
+			name: "Nested and numbered lists",
+			text: "- First point\n  - Detail\n1. Step one\n2) Step two",
+			want: []importLine{
+				{Text: "First point", Bullet: true},
+				{Text: "Detail", Bullet: true, Nesting: 1},
+				{Text: "Step one", Bullet: true, Ordered: true},
+				{Text: "Step two", Bullet: true, Ordered: true},
+			},
+		},
+		{
+			name: "Whole-line and inline emphasis",
+			text: "**Bold line**\n*Italic line*\n***Both***\nSome **strong** and _soft_ words with snake_case_name",
+			want: []importLine{
+				{Text: "Bold line", Bold: true},
+				{Text: "Italic line", Italic: true},
+				{Text: "Both", Bold: true, Italic: true},
+				{Text: "Some strong and soft words with snake_case_name"},
+			},
+		},
+		{
+			name: "Links, code, quotes and escapes",
+			text: "> Read [John 3:16](https://example.com) and `verse`\n\\# not a heading\n3\\. not a list \\*item\\*",
+			want: []importLine{
+				{Text: "Read John 3:16 and verse"},
+				{Text: "# not a heading"},
+				{Text: "3. not a list *item*"},
+			},
+		},
+		{
+			name: "Exported em spaces become tabs",
+			text: "&emsp;&emsp;Indented &amp; kept\r\n",
+			want: []importLine{{Text: "\t\tIndented & kept"}},
+		},
+		{
+			name: "Fenced blocks are kept verbatim",
+			text: "```\n**not bold**\n```",
+			want: []importLine{{Text: "**not bold**"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMarkdownInput(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMarkdownInput() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownInputReadsExport(t *testing.T) {
+	lines := parseMarkdownInput(renderMarkdown("Outline", testExportLines()))
+	if len(lines) < 3 {
+		t.Fatalf("parsed %d lines from the export, want at least 3", len(lines))
+	}
+	if lines[0].Text != "Outline" || lines[0].Heading != 1 {
+		t.Errorf("title = %+v, want level-1 heading Outline", lines[0])
+	}
+	if lines[1].Text != "Sermon Outline" || lines[1].Heading != 2 {
+		t.Errorf("heading = %+v, want level-2 heading Sermon Outline", lines[1])
+	}
+	if lines[3].Text != "God's love *never* fails" || !lines[3].Italic {
+		t.Errorf("escaped line = %+v, want italic with literal asterisks", lines[3])
+	}
+}
+
+func TestParsePlainTextInput(t *testing.T) {
+	got := parsePlainTextInput("\ufeffTitle  \r\n\n\tIndented\n\n\n")
+	want := []importLine{{Text: "Title"}, {Text: ""}, {Text: "\tIndented"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePlainTextInput() = %+v, want %+v", got, want)
+	}
+}
+
+func TestImportRequests(t *testing.T) {
+	lines := []importLine{
+		{Text: "标题 Title", Heading: 1},
+		{Text: "Bold", Bold: true},
+		{Text: "One", Bullet: true},
+		{Text: "Two", Bullet: true, Nesting: 1},
+		{Text: ""},
+		{Text: "Step", Bullet: true, Ordered: true},
+	}
+	reqs := importRequests(lines)
+	if len(reqs) != 5 {
+		t.Fatalf("got %d requests, want 5", len(reqs))
+	}
+	if got, want := reqs[0].InsertText.Text, "标题 Title\nBold\nOne\n\tTwo\n\nStep"; got != want {
+		t.Errorf("inserted text = %q, want %q", got, want)
+	}
+	if r := reqs[1].UpdateParagraphStyle; r == nil || r.ParagraphStyle.NamedStyleType != "HEADING_1" || r.Range.StartIndex != 1 || r.Range.EndIndex != 9 {
+		t.Errorf("heading request = %+v, want HEADING_1 over 1-9", reqs[1])
+	}
+	if r := reqs[2].UpdateTextStyle; r == nil || r.Fields != "bold" || r.Range.StartIndex != 10 || r.Range.EndIndex != 14 {
+		t.Errorf("bold request = %+v, want bold over 10-14", reqs[2])
+	}
+	// Lists are created from the end of the doc backwards
+	if r := reqs[3].CreateParagraphBullets; r == nil || r.BulletPreset != "NUMBERED_DECIMAL_ALPHA_ROMAN" || r.Range.StartIndex != 25 {
+		t.Errorf("numbered list request = %+v, want numbered list from 25", reqs[3])
+	}
+	if r := reqs[4].CreateParagraphBullets; r == nil || r.BulletPreset != "BULLET_DISC_CIRCLE_SQUARE" || r.Range.StartIndex != 15 || r.Range.EndIndex != 23 {
+		t.Errorf("bullet list request = %+v, want bullets over 15-23", reqs[4])
+	}
+}
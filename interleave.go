@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// translationPair is an English line and the Chinese translation that follows it in the LLM output
+type translationPair struct {
+	English string
+	Chinese string
+}
+
+// interleavePlacement is a Chinese translation to insert as a new paragraph after a source paragraph
+type interleavePlacement struct {
+	Element     *docs.StructuralElement
+	TextRun     *docs.TextRun
+	Line        DocumentLine
+	Chinese     string
+	LeadingTabs int
+}
+
+// interleavePlan is the result of matching the LLM output against the source paragraphs
+type interleavePlan struct {
+	Placements   []interleavePlacement
+	Untranslated []DocumentLine    // source lines without a translation
+	Unmatched    []translationPair // translated lines whose English is not in the source
+}
+
+// parseTranslationPairs splits bilingual LLM output into English/Chinese pairs. Consecutive
+// Chinese lines stay with the same English line; a Chinese line with no English before it
+// becomes a pair with empty English.
+func parseTranslationPairs(text string) []translationPair {
+	var pairs []translationPair
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if !isTranslationLine(classifyLineType(line)) {
+			pairs = append(pairs, translationPair{English: line})
+			continue
+		}
+		if len(pairs) == 0 {
+			pairs = append(pairs, translationPair{Chinese: line})
+			continue
+		}
+		last := &pairs[len(pairs)-1]
+		if last.Chinese == "" {
+			last.Chinese = line
+		} else {
+			last.Chinese += "\n" + line
+		}
+	}
+	return pairs
+}
+
+// planInterleave aligns the translated English lines with the source document's lines
+func planInterleave(doc *docs.Document, pairs []translationPair) (*interleavePlan, error) {
+	cursor := &DocumentCursor{Document: doc, ElementIndex: 0, LineIndex: 0}
+	var sourceLines []DocumentLine
+	var sourceInfos []*LineInfo
+	for {
+		lineInfo, err := getNextNonEmptyLine(cursor)
+		if err != nil {
+			if err.Error() == "end of document" {
+				break
+			}
+			return nil, err
+		}
+		sourceLines = append(sourceLines, DocumentLine{
+			Number: len(sourceLines) + 1,
+			Text:   lineInfo.Text,
+			Type:   classifyLineType(lineInfo.Text),
+			Key:    generateLineKey(lineInfo.Text),
+		})
+		sourceInfos = append(sourceInfos, lineInfo)
+	}
+
+	var pairLines []DocumentLine
+	var pairIndex []int
+	plan := &interleavePlan{}
+	for i, pair := range pairs {
+		if pair.English == "" {
+			plan.Unmatched = append(plan.Unmatched, pair)
+			continue
+		}
+		pairLines = append(pairLines, DocumentLine{Number: i + 1, Text: pair.English, Key: generateLineKey(pair.English)})
+		pairIndex = append(pairIndex, i)
+	}
+
+	sourceMatch, pairMatch := alignLineKeys(sourceLines, pairLines)
+	for i, partner := range sourceMatch {
+		if partner < 0 || pairs[pairIndex[partner]].Chinese == "" {
+			plan.Untranslated = append(plan.Untranslated, sourceLines[i])
+			continue
+		}
+		info := sourceInfos[i]
+		features := extractLineFeatures(info.Element, info.TextRun, info.Text)
+		plan.Placements = append(plan.Placements, interleavePlacement{
+			Element:     info.Element,
+			TextRun:     info.TextRun,
+			Line:        sourceLines[i],
+			Chinese:     pairs[pairIndex[partner]].Chinese,
+			LeadingTabs: features.LeadingTabs,
+		})
+	}
+	for j, partner := range pairMatch {
+		if partner < 0 {
+			plan.Unmatched = append(plan.Unmatched, pairs[pairIndex[j]])
+		}
+	}
+	return plan, nil
+}
+
+// buildInterleaveRequests inserts each translation as new paragraphs right after its English
+// paragraph. Splitting the English paragraph gives the new paragraphs its paragraph style; the
+// text style of its first run is then copied onto the Chinese text. Placements are processed
+// from the end of the document backwards so earlier indices stay valid.
+func buildInterleaveRequests(placements []interleavePlacement) []*docs.Request {
+	sorted := make([]interleavePlacement, len(placements))
+	copy(sorted, placements)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Element.EndIndex > sorted[j].Element.EndIndex
+	})
+
+	var requests []*docs.Request
+	for _, p := range sorted {
+		tabs := strings.Repeat("\t", p.LeadingTabs)
+		lines := strings.Split(p.Chinese, "\n")
+		for i := range lines {
+			lines[i] = tabs + lines[i]
+		}
+		text := strings.Join(lines, "\n")
+
+		// Insert before the English paragraph's trailing newline, so the new text starts
+		// at the old end index
+		insertAt := p.Element.EndIndex - 1
+		requests = append(requests, &docs.Request{
+			InsertText: &docs.InsertTextRequest{
+				Location: &docs.Location{Index: insertAt},
+				Text:     "\n" + text,
+			},
+		})
+
+		if p.TextRun != nil && p.TextRun.TextStyle != nil {
+			style := *p.TextRun.TextStyle
+			style.Link = nil
+			start := insertAt + 1
+			requests = append(requests, &docs.Request{
+				UpdateTextStyle: &docs.UpdateTextStyleRequest{
+					Range:     &docs.Range{StartIndex: start, EndIndex: start + int64(len(utf16.Encode([]rune(text))))},
+					TextStyle: &style,
+					Fields:    "*",
+				},
+			})
+		}
+	}
+	return requests
+}
+
+// interleaveTranslation inserts the translation under each matching paragraph of the document
+func interleaveTranslation(ctx context.Context, docsService *docs.Service, docID, translation string) (*interleavePlan, error) {
+	doc, err := getDocument(ctx, docsService, docID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve document: %w", err)
+	}
+	plan, err := planInterleave(doc, parseTranslationPairs(translation))
+	if err != nil {
+		return nil, fmt.Errorf("error reading document: %w", err)
+	}
+	if len(plan.Placements) == 0 {
+		return nil, fmt.Errorf("no translated line matches the document")
+	}
+
+	requests := buildInterleaveRequests(plan.Placements)
+	_, err = batchUpdateDocument(ctx, docsService, docID, &docs.BatchUpdateDocumentRequest{Requests: requests})
+	if err != nil {
+		return nil, fmt.Errorf("batch update failed: %w", err)
+	}
+	return plan, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+	"unicode/utf16"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// buildParagraphDocument builds a document with one paragraph per text, indexed like the Docs API
+func buildParagraphDocument(texts ...string) *docs.Document {
+	doc := &docs.Document{Body: &docs.Body{}}
+	index := int64(1)
+	for _, text := range texts {
+		content := text + "\n"
+		end := index + int64(len(utf16.Encode([]rune(content))))
+		doc.Body.Content = append(doc.Body.Content, &docs.StructuralElement{
+			StartIndex: index,
+			EndIndex:   end,
+			Paragraph: &docs.Paragraph{
+				Elements: []*docs.ParagraphElement{{
+					StartIndex: index,
+					EndIndex:   end,
+					TextRun:    &docs.TextRun{Content: content, TextStyle: &docs.TextStyle{Bold: true}},
+				}},
+				ParagraphStyle: &docs.ParagraphStyle{Alignment: "CENTER"},
+			},
+		})
+		index = end
+	}
+	return doc
+}
+
+func TestParseTranslationPairs(t *testing.T) {
+	text := "Title\n标题\n\nFirst line.\r\n第一行。\n第一行续。\nNo translation\n孤立的中文"
+	pairs := parseTranslationPairs(text)
+
+	expected := []translationPair{
+		{English: "Title", Chinese: "标题"},
+		{English: "First line.", Chinese: "第一行。\n第一行续。"},
+		{English: "No translation", Chinese: "孤立的中文"},
+	}
+	if len(pairs) != len(expected) {
+		t.Fatalf("parseTranslationPairs() returned %d pairs, want %d: %+v", len(pairs), len(expected), pairs)
+	}
+	for i := range expected {
+		if pairs[i] != expected[i] {
+			t.Errorf("pair %d = %+v, want %+v", i, pairs[i], expected[i])
+		}
+	}
+
+	leading := parseTranslationPairs("开头\nHello\n你好")
+	if len(leading) != 2 || leading[0].English != "" || leading[0].Chinese != "开头" {
+		t.Errorf("leading Chinese line should become a pair without English, got %+v", leading)
+	}
+}
+
+func TestPlanInterleave(t *testing.T) {
+	doc := buildParagraphDocument("Sunday Sermon", "\tGrace and peace.", "Closing prayer")
+	pairs := []translationPair{
+		{English: "Sunday Sermon", Chinese: "主日讲章"},
+		{English: "Grace and peace.", Chinese: "恩典与平安。"},
+		{English: "An extra line", Chinese: "多出的一行"},
+	}
+
+	plan, err := planInterleave(doc, pairs)
+	if err != nil {
+		t.Fatalf("planInterleave() error = %v", err)
+	}
+	if len(plan.Placements) != 2 {
+		t.Fatalf("placements = %d, want 2", len(plan.Placements))
+	}
+	if plan.Placements[1].LeadingTabs != 1 {
+		t.Errorf("leading tabs = %d, want 1", plan.Placements[1].LeadingTabs)
+	}
+	if len(plan.Untranslated) != 1 || plan.Untranslated[0].Text != "Closing prayer" {
+		t.Errorf("untranslated = %+v, want [Closing prayer]", plan.Untranslated)
+	}
+	if len(plan.Unmatched) != 1 || plan.Unmatched[0].English != "An extra line" {
+		t.Errorf("unmatched = %+v, want [An extra line]", plan.Unmatched)
+	}
+
+	requests := buildInterleaveRequests(plan.Placements)
+	if len(requests) != 4 {
+		t.Fatalf("requests = %d, want 4", len(requests))
+	}
+
+	// The last paragraph is handled first so that earlier indices stay valid
+	second := doc.Body.Content[1]
+	if got := requests[0].InsertText; got == nil || got.Location.Index != second.EndIndex-1 || got.Text != "\n\t恩典与平安。" {
+		t.Errorf("first request = %+v, want insert of the second paragraph's translation at %d", got, second.EndIndex-1)
+	}
+	if got := requests[1].UpdateTextStyle; got == nil || got.Range.StartIndex != second.EndIndex || got.Range.EndIndex != second.EndIndex+7 || !got.TextStyle.Bold {
+		t.Errorf("second request = %+v, want bold style over the inserted text", got)
+	}
+	first := doc.Body.Content[0]
+	if got := requests[2].InsertText; got == nil || got.Location.Index != first.EndIndex-1 || got.Text != "\n主日讲章" {
+		t.Errorf("third request = %+v, want insert of the first paragraph's translation", got)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// logOptions holds the global logging flags shared by all commands
+type logOptions struct {
+	Verbose bool
+	Quiet   bool
+	Format  string // text or json
+}
+
+// newLogger builds a leveled logger writing to w according to the global logging flags
+func newLogger(w io.Writer, opts logOptions) (*slog.Logger, error) {
+	if opts.Verbose && opts.Quiet {
+		return nil, fmt.Errorf("--verbose and --quiet cannot be combined")
+	}
+
+	level := slog.LevelInfo
+	if opts.Verbose {
+		level = slog.LevelDebug
+	} else if opts.Quiet {
+		level = slog.LevelWarn
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+
+	switch opts.Format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, handlerOpts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, handlerOpts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (expected text or json)", opts.Format)
+	}
+}
+
+// logFatal logs an error-level event and exits the process
+func logFatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// logLineDecision emits the decision made for a single target line as a structured event at debug
+// level; the default level only shows summaries
+func logLineDecision(loopID, sourceLine, targetLine int, decision *MatchDecision, action, text string) {
+	slog.Debug("line decision",
+		"loop", loopID,
+		"source_line", sourceLine,
+		"target_line", targetLine,
+		"line_type", decision.LineType.String(),
+		"lines_match", decision.LinesMatch,
+		"action", action,
+		"text", text,
+	)
+}
+
+// logLineFeatures emits the formatting about to be applied to a range at debug level
+func logLineFeatures(msg string, startIndex, endIndex int64, features *LineFeatures) {
+	if features == nil {
+		return
+	}
+	attrs := []any{
+		"start", startIndex,
+		"end", endIndex,
+		"alignment", features.Alignment,
+		"has_bullet", features.HasBullet,
+		"leading_tabs", features.LeadingTabs,
+	}
+	if features.FirstLineIndent != nil {
+		attrs = append(attrs, "first_line_indent", *features.FirstLineIndent)
+	}
+	if features.LeftIndent != nil {
+		attrs = append(attrs, "left_indent", *features.LeftIndent)
+	}
+	if features.RightIndent != nil {
+		attrs = append(attrs, "right_indent", *features.RightIndent)
+	}
+	slog.Debug(msg, attrs...)
+}
@@ -2,24 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
-	"time"
 	"unicode/utf16"
 
 	"google.golang.org/api/docs/v1"
-	"google.golang.org/api/drive/v2"
-	"google.golang.org/api/option"
-)
-
-// Configuration constants
-const (
-	// FormattingDelaySeconds configures the delay after each formatting application
-	FormattingDelaySeconds = 1
+	"google.golang.org/api/drive/v3"
 )
 
 // Data structures for dual-document synchronization
@@ -99,49 +93,209 @@ type BatchUpdateManager struct {
 	DocID   string
 }
 
+// parseFlags parses command-line flags and exits with ExitError on a bad flag, instead of the flag
+// package's own status 2, which is also the status of a Go panic.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(ExitError)
+	}
+}
+
 func main() {
-	if len(os.Args) < 2 {
+	// Global flags come before the command name and apply to every command
+	globalFlags := flag.NewFlagSet("googledoc-reader", flag.ContinueOnError)
+	var logOpts logOptions
+	globalFlags.BoolVar(&logOpts.Verbose, "verbose", false, "")
+	globalFlags.BoolVar(&logOpts.Quiet, "quiet", false, "")
+	globalFlags.StringVar(&logOpts.Format, "log-format", "text", "")
+	var authOpts authFlags
+	globalFlags.StringVar(&authOpts.ConfigFile, "config", "", "")
+	globalFlags.StringVar(&authOpts.ConfigDir, "config-dir", "", "")
+	globalFlags.StringVar(&authOpts.CredentialsFile, "credentials", "", "")
+	globalFlags.StringVar(&authOpts.OAuthClientFile, "oauth-client", "", "")
+	globalFlags.StringVar(&authOpts.TokenFile, "token", "", "")
+	globalFlags.BoolVar(&authOpts.ApplicationDefaultCredentials, "adc", false, "")
+	parseFlags(globalFlags, os.Args[1:])
+	cmdArgs := globalFlags.Args()
+
+	logger, err := newLogger(os.Stderr, logOpts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	configPath := authOpts.ConfigFile
+	if configPath == "" {
+		configPath = filepath.Join(authOpts.ConfigDir, defaultConfigFile)
+	}
+	cfg, err := loadAppConfig(configPath)
+	if err != nil {
+		logFatal("failed to load config", "path", configPath, "err", err)
+	}
+	auth := resolveAuth(authOpts, cfg.Auth, os.Getenv, fileExists)
+
+	// Services are built once per run, after the command line has been validated
+	getClients := func() *clients {
+		c, err := newClients(context.Background(), auth)
+		if err != nil {
+			logFatal("unable to set up Google API access", "err", err)
+		}
+		return c
+	}
+
+	if len(cmdArgs) < 1 {
 		showUsage()
 		os.Exit(1)
 	}
 
-	switch os.Args[1] {
+	switch cmdArgs[0] {
 	case "analyze":
-		if len(os.Args) < 3 {
+		if len(cmdArgs) < 2 {
 			fmt.Println("Usage: go run main.go analyze <google-docs-url>")
 			os.Exit(1)
 		}
-		analyzeDocument(os.Args[2])
+		analyzeDocument(getClients(), cmdArgs[1])
+	case "auth":
+		runAuthCommand(auth, cmdArgs[1:])
+	case "cache":
+		runCacheCommand(cmdArgs[1:])
 	case "e2e":
-		runE2E()
+		fs := flag.NewFlagSet("e2e", flag.ContinueOnError)
+		mode := fs.String("mode", "", "")
+		var opts e2eOptions
+		fs.BoolVar(&opts.Yes, "yes", false, "")
+		fs.BoolVar(&opts.StopAfterWrite, "stop-after-write", false, "")
+		fs.DurationVar(&opts.ReviewTimeout, "review-timeout", 0, "")
+		fs.StringVar(&opts.ResumeRunID, "resume", "", "")
+		fs.BoolVar(&opts.NoCache, "no-cache", false, "")
+		parseFlags(fs, cmdArgs[1:])
+		if err := validateE2EOptions(opts, *mode); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(ExitError)
+		}
+		if *mode != "" {
+			cfg.OutputMode = *mode
+		}
+		runE2E(getClients(), cfg, opts)
+	case "convert-script":
+		fs := flag.NewFlagSet("convert-script", flag.ContinueOnError)
+		to := fs.String("to", "", "")
+		pinyin := fs.Bool("pinyin", false, "")
+		inPlace := fs.Bool("in-place", false, "")
+		dryRun := fs.Bool("dry-run", false, "")
+		parseFlags(fs, cmdArgs[1:])
+		args := fs.Args()
+		target := strings.ToLower(strings.TrimSpace(*to))
+		if len(args) < 1 || (target == "" && !*pinyin) || (target != "" && target != ScriptTraditional && target != ScriptSimplified) {
+			fmt.Println("Usage: go run main.go convert-script [--to traditional|simplified] [--pinyin [--in-place]] [--dry-run] <bilingual-doc-url>")
+			os.Exit(1)
+		}
+		convertScript(getClients(), args[0], target, *pinyin, *inPlace, *dryRun)
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ContinueOnError)
+		format := fs.String("format", ExportPDF, "")
+		lang := fs.String("lang", ExportBoth, "")
+		outPath := fs.String("out", "", "")
+		parseFlags(fs, cmdArgs[1:])
+		args := fs.Args()
+		if len(args) < 1 {
+			fmt.Println("Usage: go run main.go export [--format docx|pdf|md|html|txt] [--lang both|en|zh] [--out FILE] <google-docs-url>")
+			os.Exit(1)
+		}
+		validFormat, validLang, err := validateExportOptions(*format, *lang)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		exportDocument(getClients(), args[0], validFormat, validLang, *outPath)
+	case "harvest":
+		if len(cmdArgs) < 2 {
+			fmt.Println("Usage: go run main.go harvest <bilingual-doc-url>...")
+			os.Exit(1)
+		}
+		harvestDocuments(getClients(), firstNonEmpty(cfg.TranslationMemoryFile, defaultTranslationMemoryFile), cmdArgs[1:])
+	case "qa":
+		fs := flag.NewFlagSet("qa", flag.ContinueOnError)
+		comments := fs.Bool("comments", false, "")
+		dryRun := fs.Bool("dry-run", false, "")
+		parseFlags(fs, cmdArgs[1:])
+		args := fs.Args()
+		if len(args) < 1 {
+			fmt.Println("Usage: go run main.go qa [--comments] [--dry-run] <bilingual-doc-url>")
+			os.Exit(1)
+		}
+		checkTranslationQuality(getClients(), cfg, args[0], *comments, *dryRun)
+	case "slides":
+		fs := flag.NewFlagSet("slides", flag.ContinueOnError)
+		title := fs.String("title", "", "")
+		maxPairs := fs.Int("max-pairs", defaultSlidePairs, "")
+		parseFlags(fs, cmdArgs[1:])
+		args := fs.Args()
+		if len(args) < 1 || *maxPairs < 1 {
+			fmt.Println("Usage: go run main.go slides [--title TITLE] [--max-pairs N] <bilingual-doc-url>")
+			os.Exit(1)
+		}
+		generateSlides(getClients(), args[0], *title, *maxPairs)
+	case "update":
+		fs := flag.NewFlagSet("update", flag.ContinueOnError)
+		runID := fs.String("run", "", "")
+		noCache := fs.Bool("no-cache", false, "")
+		dryRun := fs.Bool("dry-run", false, "")
+		parseFlags(fs, cmdArgs[1:])
+		updateBilingualDoc(getClients(), cfg, *runID, *noCache, *dryRun)
 	case "sync-format":
-		fs := flag.NewFlagSet("sync-format", flag.ExitOnError)
+		fs := flag.NewFlagSet("sync-format", flag.ContinueOnError)
 		startLoop := fs.Int("start-loop", 1, "")
-		_ = fs.Parse(os.Args[2:])
+		reverse := fs.Bool("reverse", false, "")
+		reportPath := fs.String("report", "", "")
+		parseFlags(fs, cmdArgs[1:])
 		args := fs.Args()
 		if len(args) < 2 {
-			fmt.Println("Usage: go run main.go sync-format [--start-loop N] <source-doc-url> <target-doc-url>")
+			fmt.Println("Usage: go run main.go sync-format [--start-loop N] [--reverse] [--report FILE.html] <source-doc-url> <target-doc-url>")
 			os.Exit(1)
 		}
 		if *startLoop < 1 {
 			fmt.Println("Error: --start-loop must be >= 1")
 			os.Exit(1)
 		}
-		syncDocumentFormatting(args[0], args[1], *startLoop)
+		if *reverse {
+			if *startLoop != 1 || *reportPath != "" {
+				fmt.Println("Error: --start-loop and --report cannot be combined with --reverse")
+				os.Exit(1)
+			}
+			syncDocumentFormattingReverse(getClients(), args[0], args[1])
+			return
+		}
+		syncDocumentFormatting(getClients(), args[0], args[1], *startLoop, *reportPath)
+	case "compare":
+		fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+		reportPath := fs.String("report", "", "")
+		parseFlags(fs, cmdArgs[1:])
+		args := fs.Args()
+		if len(args) < 2 {
+			fmt.Println("Usage: go run main.go compare [--report FILE.html] <source-doc-url> <target-doc-url>")
+			os.Exit(1)
+		}
+		compareDocuments(getClients(), args[0], args[1], *reportPath)
 	case "test-action":
-		if len(os.Args) < 3 {
+		if len(cmdArgs) < 2 {
 			fmt.Println("Usage: go run main.go test-action <google-docs-url>")
 			os.Exit(1)
 		}
-		testAction(os.Args[2])
+		testAction(getClients(), cmdArgs[1])
 	case "add-spacing":
-		if len(os.Args) < 3 {
+		if len(cmdArgs) < 2 {
 			fmt.Println("Usage: go run main.go add-spacing <google-docs-url>")
 			os.Exit(1)
 		}
-		addSpacingAfterChineseLines(os.Args[2])
+		addSpacingAfterChineseLines(getClients(), cmdArgs[1])
 	default:
-		fmt.Printf("Unknown command: %s\n", os.Args[1])
+		fmt.Printf("Unknown command: %s\n", cmdArgs[0])
 		showUsage()
 		os.Exit(1)
 	}
@@ -150,76 +304,109 @@ func main() {
 func showUsage() {
 	fmt.Println("Google Docs Tool")
 	fmt.Println("Usage:")
+	fmt.Println("  go run main.go [global flags] <command> ...")
 	fmt.Println("  go run main.go analyze <google-docs-url>")
-	fmt.Println("  go run main.go e2e")
-	fmt.Println("  go run main.go sync-format [--start-loop N] <source-doc-url> <target-doc-url>")
+	fmt.Println("  go run main.go auth login|status|logout")
+	fmt.Println("  go run main.go e2e [--mode blank|copy|interleave|table] [--yes | --stop-after-write | --review-timeout DURATION] [--resume RUN-ID] [--no-cache]")
+	fmt.Println("  go run main.go cache list|clear")
+	fmt.Println("  go run main.go convert-script [--to traditional|simplified] [--pinyin [--in-place]] [--dry-run] <bilingual-doc-url>")
+	fmt.Println("  go run main.go export [--format docx|pdf|md|html|txt] [--lang both|en|zh] [--out FILE] <google-docs-url>")
+	fmt.Println("  go run main.go harvest <bilingual-doc-url>...")
+	fmt.Println("  go run main.go qa [--comments] [--dry-run] <bilingual-doc-url>")
+	fmt.Println("  go run main.go slides [--title TITLE] [--max-pairs N] <bilingual-doc-url>")
+	fmt.Println("  go run main.go update [--run RUN-ID] [--dry-run] [--no-cache]")
+	fmt.Println("  go run main.go sync-format [--start-loop N] [--reverse] [--report FILE.html] <source-doc-url> <target-doc-url>")
+	fmt.Println("  go run main.go compare [--report FILE.html] <source-doc-url> <target-doc-url>")
 	fmt.Println("  go run main.go test-action <google-docs-url>")
 	fmt.Println("  go run main.go add-spacing <google-docs-url>")
 	fmt.Println("")
 	fmt.Println("Commands:")
 	fmt.Println("  analyze      Analyze document formatting (first 100 lines)")
+	fmt.Println("  auth         Log in to Drive in the browser, show credential status, or log out")
+	fmt.Println("  cache        List or clear cached LLM translations")
 	fmt.Println("  e2e          Translate input doc to new output doc, then sync formatting")
-	fmt.Println("  sync-format  Synchronize formatting from source to target document")
+	fmt.Println("  convert-script Convert the Chinese lines between Simplified and Traditional and/or add pinyin lines (to a copy)")
+	fmt.Println("  export       Save a document as DOCX/PDF (via Drive) or Markdown/HTML/text, optionally one language only")
+	fmt.Println("  harvest      Store the reviewed line pairs of bilingual docs in the translation memory")
+	fmt.Println("  qa           Flag suspicious translations in a bilingual doc with a highlight or a comment")
+	fmt.Println("  slides       Create a Google Slides presentation from the line pairs of a bilingual doc")
+	fmt.Println("  update       Translate source lines edited since the last e2e run into its bilingual doc")
+	fmt.Println("  sync-format  Synchronize formatting from source to target document (--reverse: target to source)")
+	fmt.Println("  compare      Report structural drift between source and bilingual target")
 	fmt.Println("  test-action  Test action for development purposes")
 	fmt.Println("  add-spacing  Add empty line after lines starting with Chinese characters")
 	fmt.Println("")
+	fmt.Println("Global flags:")
+	fmt.Println("  --verbose     Log debug details such as the formatting applied to every line")
+	fmt.Println("  --quiet       Only log warnings and errors")
+	fmt.Println("  --log-format  Log output format: text (default) or json")
+	fmt.Println("  --config      Path to application.yaml (default: <config-dir>/application.yaml)")
+	fmt.Println("  --config-dir  Directory holding application.yaml and credential files (default: current directory)")
+	fmt.Println("  --credentials Service account key for the Docs API (default: $GOOGLE_APPLICATION_CREDENTIALS, then churchoutline.json)")
+	fmt.Println("  --oauth-client OAuth client config used to create docs as you (default: client_json)")
+	fmt.Println("  --token       OAuth token cache (default: token.json)")
+	fmt.Println("  --adc         Use application-default credentials instead of a service account key")
+	fmt.Println("")
 	fmt.Println("Examples:")
 	fmt.Println("  go run main.go analyze \"https://docs.google.com/document/d/12sJRJ57pNy9zJ6YMD9_HRNuD_UPuWEWnjIBxvul8sRQ/edit\"")
+	fmt.Println("  go run main.go auth login")
 	fmt.Println("  go run main.go e2e")
+	fmt.Println("  go run main.go e2e --mode copy")
+	fmt.Println("  go run main.go e2e --mode interleave")
+	fmt.Println("  go run main.go e2e --mode table")
+	fmt.Println("  go run main.go e2e --review-timeout 30m")
+	fmt.Println("  go run main.go e2e --resume 20240310-093000-1a2b3c")
+	fmt.Println("  go run main.go convert-script --to traditional \"<bilingual-doc-url>\"")
+	fmt.Println("  go run main.go convert-script --pinyin --dry-run \"<bilingual-doc-url>\"")
+	fmt.Println("  go run main.go export --format md --lang zh \"<bilingual-doc-url>\"")
+	fmt.Println("  go run main.go harvest \"<bilingual-doc-url>\"")
+	fmt.Println("  go run main.go qa --dry-run \"<bilingual-doc-url>\"")
+	fmt.Println("  go run main.go slides --max-pairs 2 \"<bilingual-doc-url>\"")
+	fmt.Println("  go run main.go update --dry-run")
 	fmt.Println("  go run main.go sync-format \"<source-url>\" \"<target-url>\"")
 	fmt.Println("  go run main.go sync-format --start-loop 200 \"<source-url>\" \"<target-url>\"")
+	fmt.Println("  go run main.go sync-format --reverse \"<source-url>\" \"<target-url>\"")
+	fmt.Println("  go run main.go sync-format --report sync.html \"<source-url>\" \"<target-url>\"")
+	fmt.Println("  go run main.go compare \"<source-url>\" \"<target-url>\"")
+	fmt.Println("  go run main.go --quiet --log-format json sync-format \"<source-url>\" \"<target-url>\"")
 	fmt.Println("  go run main.go test-action \"<document-url>\"")
 	fmt.Println("  go run main.go add-spacing \"<document-url>\"")
 }
 
-func analyzeDocument(docURL string) {
+func analyzeDocument(c *clients, docURL string) {
 	docID := extractDocumentID(docURL)
 	if docID == "" {
-		log.Fatal("Invalid Google Docs URL. Please provide a valid document URL.")
+		logFatal("invalid Google Docs URL, please provide a valid document URL", "url", docURL)
 	}
 
-	fmt.Printf("Document ID: %s\n", docID)
+	slog.Info("analyzing document", "doc_id", docID)
 
-	firstLine, err := getFirstLineFromDoc(docID)
+	firstLine, err := getFirstLineFromDoc(context.Background(), c.Docs, docID)
 	if err != nil {
-		log.Fatalf("Error reading document: %v", err)
+		logFatal("error reading document", "err", err)
 	}
 
 	fmt.Printf("First line: %s\n", firstLine)
 }
 
 // testAction updates a Google Doc by converting lines starting with '·' into proper bullet points
-func testAction(docURL string) {
+func testAction(c *clients, docURL string) {
 	docID := extractDocumentID(docURL)
 	if docID == "" {
-		log.Fatal("Invalid Google Docs URL. Please provide a valid document URL.")
+		logFatal("invalid Google Docs URL, please provide a valid document URL", "url", docURL)
 	}
 
-	fmt.Printf("Document ID: %s\n", docID)
+	slog.Info("running test action", "doc_id", docID)
 
-	err := convertDotLinesToBullets(docID)
+	err := convertDotLinesToBullets(context.Background(), c.Docs, docID)
 	if err != nil {
-		log.Fatalf("Error updating document: %v", err)
+		logFatal("error updating document", "err", err)
 	}
 }
 
-func convertDotLinesToBullets(docID string) error {
-	ctx := context.Background()
-
-	// Load credentials
-	credentialsFile := "churchoutline.json"
-	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
-		return fmt.Errorf("churchoutline.json not found. Please follow setup instructions in README.md")
-	}
-
-	// Create Docs service with write permissions
-	docsService, err := docs.NewService(ctx, option.WithCredentialsFile(credentialsFile), option.WithScopes(docs.DocumentsScope))
-	if err != nil {
-		return fmt.Errorf("unable to create Docs service: %v", err)
-	}
-
+func convertDotLinesToBullets(ctx context.Context, docsService *docs.Service, docID string) error {
 	// Get document to analyze content
-	doc, err := docsService.Documents.Get(docID).Do()
+	doc, err := getDocument(ctx, docsService, docID)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve document: %v", err)
 	}
@@ -293,14 +480,14 @@ func convertDotLinesToBullets(docID string) error {
 	}
 
 	if len(requests) == 0 {
-		fmt.Println("No lines starting with '·' found.")
+		slog.Info("no lines starting with '·' found")
 		return nil
 	}
 
-	fmt.Printf("Converting %d lines starting with '·' into bullet points...\n", updatedCount)
+	slog.Info("converting lines starting with '·' into bullet points", "lines", updatedCount)
 
 	batchUpdateRequest := &docs.BatchUpdateDocumentRequest{Requests: requests}
-	_, err = docsService.Documents.BatchUpdate(docID, batchUpdateRequest).Do()
+	_, err = batchUpdateDocument(ctx, docsService, docID, batchUpdateRequest)
 	if err != nil {
 		return fmt.Errorf("failed to update document: %v", err)
 	}
@@ -308,73 +495,28 @@ func convertDotLinesToBullets(docID string) error {
 	return nil
 }
 
-// createNewDocWithPublicEdit creates a new Google Doc and sets permissions for anyone with the link to edit
-func createNewDocWithPublicEdit() error {
-	ctx := context.Background()
-
-	// Load credentials
-	credentialsFile := "churchoutline.json"
-	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
-		return fmt.Errorf("churchoutline.json not found. Please follow setup instructions in README.md")
-	}
-
-	// Create Drive service with full permissions
-	driveService, err := drive.NewService(ctx, option.WithCredentialsFile(credentialsFile), option.WithScopes(drive.DriveFileScope))
-	if err != nil {
-		return fmt.Errorf("unable to create Drive service: %v", err)
-	}
-
+// createNewDoc creates a new Google Doc and shares it according to the sharing policy
+func createNewDoc(ctx context.Context, driveService *drive.Service, sharing sharingConfig) error {
 	// Create a new Google Doc
-	doc := &drive.File{
-		Title:    "New Document",
-		MimeType: "application/vnd.google-apps.document",
-	}
-
-	createdFile, err := driveService.Files.Insert(doc).Fields("id, title, alternateLink").Do()
+	createdFile, err := createOutputDoc(ctx, driveService, "New Document", "")
 	if err != nil {
-		return fmt.Errorf("unable to create document: %v", err)
+		return err
 	}
 
-	fmt.Printf("Document created successfully!\n")
-	fmt.Printf("Document ID: %s\n", createdFile.Id)
-	fmt.Printf("Document Name: %s\n", createdFile.Title)
-	fmt.Printf("Document URL: %s\n", createdFile.AlternateLink)
+	slog.Info("document created", "doc_id", createdFile.Id, "name", createdFile.Name, "url", createdFile.WebViewLink)
 
-	// Set permissions: anyone with the link can edit
-	permission := &drive.Permission{
-		Type: "anyone",
-		Role: "writer",
-	}
-
-	_, err = driveService.Permissions.Insert(createdFile.Id, permission).Do()
-	if err != nil {
+	// Private by default; only the configured users, groups, domain or link get access
+	if err := applySharingPolicy(ctx, driveService, createdFile.Id, sharing); err != nil {
 		return fmt.Errorf("unable to set permissions: %v", err)
 	}
 
-	fmt.Println("\nPermissions set: Anyone with the link can edit")
-	fmt.Println("Test action completed successfully!")
-
 	return nil
 }
 
 // insertTabsAtLineStart inserts a tab character at the beginning of every line in the document
-func insertTabsAtLineStart(docID string) error {
-	ctx := context.Background()
-
-	// Load credentials
-	credentialsFile := "churchoutline.json"
-	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
-		return fmt.Errorf("churchoutline.json not found. Please follow setup instructions in README.md")
-	}
-
-	// Create Docs service with write permissions
-	docsService, err := docs.NewService(ctx, option.WithCredentialsFile(credentialsFile), option.WithScopes(docs.DocumentsScope))
-	if err != nil {
-		return fmt.Errorf("unable to create Docs service: %v", err)
-	}
-
+func insertTabsAtLineStart(ctx context.Context, docsService *docs.Service, docID string) error {
 	// Get document to find all paragraph ranges
-	doc, err := docsService.Documents.Get(docID).Do()
+	doc, err := getDocument(ctx, docsService, docID)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve document: %v", err)
 	}
@@ -418,14 +560,14 @@ func insertTabsAtLineStart(docID string) error {
 		return fmt.Errorf("no paragraphs found to update")
 	}
 
-	fmt.Printf("Inserting tabs at the beginning of %d lines...\n", len(requests))
+	slog.Info("inserting tabs at the beginning of lines", "lines", len(requests))
 
 	// Execute batch update
 	batchUpdateRequest := &docs.BatchUpdateDocumentRequest{
 		Requests: requests,
 	}
 
-	_, err = docsService.Documents.BatchUpdate(docID, batchUpdateRequest).Do()
+	_, err = batchUpdateDocument(ctx, docsService, docID, batchUpdateRequest)
 	if err != nil {
 		return fmt.Errorf("failed to insert tabs: %v", err)
 	}
@@ -434,40 +576,26 @@ func insertTabsAtLineStart(docID string) error {
 }
 
 // centerAlignDocument aligns all lines in a document to center
-func centerAlignDocument(docURL string) {
+func centerAlignDocument(c *clients, docURL string) {
 	docID := extractDocumentID(docURL)
 	if docID == "" {
-		log.Fatal("Invalid Google Docs URL. Please provide a valid document URL.")
+		logFatal("invalid Google Docs URL, please provide a valid document URL", "url", docURL)
 	}
 
-	fmt.Printf("Document ID: %s\n", docID)
+	slog.Info("center aligning document", "doc_id", docID)
 
-	err := applyCenterAlignment(docID)
+	err := applyCenterAlignment(context.Background(), c.Docs, docID)
 	if err != nil {
-		log.Fatalf("Error applying center alignment: %v", err)
+		logFatal("error applying center alignment", "err", err)
 	}
 
-	fmt.Println("Document center alignment completed successfully!")
+	slog.Info("document center alignment completed successfully")
 }
 
 // applyCenterAlignment applies center alignment to all paragraphs in the document
-func applyCenterAlignment(docID string) error {
-	ctx := context.Background()
-
-	// Load credentials
-	credentialsFile := "churchoutline.json"
-	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
-		return fmt.Errorf("churchoutline.json not found. Please follow setup instructions in README.md")
-	}
-
-	// Create Docs service with write permissions
-	docsService, err := docs.NewService(ctx, option.WithCredentialsFile(credentialsFile), option.WithScopes(docs.DocumentsScope))
-	if err != nil {
-		return fmt.Errorf("unable to create Docs service: %v", err)
-	}
-
+func applyCenterAlignment(ctx context.Context, docsService *docs.Service, docID string) error {
 	// Get document to find all paragraph ranges
-	doc, err := docsService.Documents.Get(docID).Do()
+	doc, err := getDocument(ctx, docsService, docID)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve document: %v", err)
 	}
@@ -499,14 +627,14 @@ func applyCenterAlignment(docID string) error {
 		return fmt.Errorf("no paragraphs found to update")
 	}
 
-	fmt.Printf("Applying center alignment to %d paragraphs...\n", len(requests))
+	slog.Info("applying center alignment", "paragraphs", len(requests))
 
 	// Execute batch update
 	batchUpdateRequest := &docs.BatchUpdateDocumentRequest{
 		Requests: requests,
 	}
 
-	_, err = docsService.Documents.BatchUpdate(docID, batchUpdateRequest).Do()
+	_, err = batchUpdateDocument(ctx, docsService, docID, batchUpdateRequest)
 	if err != nil {
 		return fmt.Errorf("failed to apply center alignment: %v", err)
 	}
@@ -515,40 +643,26 @@ func applyCenterAlignment(docID string) error {
 }
 
 // addSpacingAfterChineseLines adds empty lines after lines that start with Chinese characters
-func addSpacingAfterChineseLines(docURL string) {
+func addSpacingAfterChineseLines(c *clients, docURL string) {
 	docID := extractDocumentID(docURL)
 	if docID == "" {
-		log.Fatal("Invalid Google Docs URL. Please provide a valid document URL.")
+		logFatal("invalid Google Docs URL, please provide a valid document URL", "url", docURL)
 	}
 
-	fmt.Printf("Document ID: %s\n", docID)
+	slog.Info("adding spacing after Chinese lines", "doc_id", docID)
 
-	err := applyChineseLineSpacing(docID)
+	err := applyChineseLineSpacing(context.Background(), c.Docs, docID)
 	if err != nil {
-		log.Fatalf("Error adding spacing after Chinese lines: %v", err)
+		logFatal("error adding spacing after Chinese lines", "err", err)
 	}
 
-	fmt.Println("Chinese line spacing completed successfully!")
+	slog.Info("Chinese line spacing completed successfully")
 }
 
 // applyChineseLineSpacing adds empty lines after lines that start with Chinese characters
-func applyChineseLineSpacing(docID string) error {
-	ctx := context.Background()
-
-	// Load credentials
-	credentialsFile := "churchoutline.json"
-	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
-		return fmt.Errorf("churchoutline.json not found. Please follow setup instructions in README.md")
-	}
-
-	// Create Docs service with write permissions
-	docsService, err := docs.NewService(ctx, option.WithCredentialsFile(credentialsFile), option.WithScopes(docs.DocumentsScope))
-	if err != nil {
-		return fmt.Errorf("unable to create Docs service: %v", err)
-	}
-
+func applyChineseLineSpacing(ctx context.Context, docsService *docs.Service, docID string) error {
 	// Get document to analyze content
-	doc, err := docsService.Documents.Get(docID).Do()
+	doc, err := getDocument(ctx, docsService, docID)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve document: %v", err)
 	}
@@ -585,18 +699,18 @@ func applyChineseLineSpacing(docID string) error {
 	}
 
 	if len(insertRequests) == 0 {
-		fmt.Println("No lines starting with Chinese characters found.")
+		slog.Info("no lines starting with Chinese characters found")
 		return nil
 	}
 
-	fmt.Printf("Adding empty lines after %d Chinese lines...\n", len(insertRequests))
+	slog.Info("adding empty lines after Chinese lines", "lines", len(insertRequests))
 
 	// Execute batch update
 	batchUpdateRequest := &docs.BatchUpdateDocumentRequest{
 		Requests: insertRequests,
 	}
 
-	_, err = docsService.Documents.BatchUpdate(docID, batchUpdateRequest).Do()
+	_, err = batchUpdateDocument(ctx, docsService, docID, batchUpdateRequest)
 	if err != nil {
 		return fmt.Errorf("failed to add spacing after Chinese lines: %v", err)
 	}
@@ -604,30 +718,10 @@ func applyChineseLineSpacing(docID string) error {
 	return nil
 }
 
-// applyFormattingToRange applies LineFeatures to a specific range in the target document
-func applyFormattingToRange(docsService *docs.Service, docID string, startIndex, endIndex int64, features *LineFeatures) error {
-
-	// Add configurable delay after formatting application to avoid API rate limits
-	time.Sleep(time.Duration(FormattingDelaySeconds) * time.Second)
-	fmt.Printf("Applying features to range [%d,%d)\n", startIndex, endIndex)
-
+// formattingRequests builds the paragraph and text style updates that give a range the features
+func formattingRequests(startIndex, endIndex int64, features *LineFeatures) []*docs.Request {
 	var requests []*docs.Request
 
-	// Print all the features applied to this line
-	fmt.Println("Applying the following features to lines:")
-	fmt.Printf("Alignment: %s\n", features.Alignment)
-	if features.FirstLineIndent != nil {
-		fmt.Printf("First Line Indent: %f\n", *features.FirstLineIndent)
-	}
-	if features.LeftIndent != nil {
-		fmt.Printf("Left Indent: %f\n", *features.LeftIndent)
-	}
-	if features.RightIndent != nil {
-		fmt.Printf("Right Indent: %f\n", *features.RightIndent)
-	}
-	fmt.Printf("Has Bullet: %t\n", features.HasBullet)
-	fmt.Printf("Leading Tabs: %d\n", features.LeadingTabs)
-
 	// Apply paragraph style (alignment, indentation, bullets)
 	if features.Alignment != "" || features.FirstLineIndent != nil || features.LeftIndent != nil || features.RightIndent != nil || features.HasBullet {
 		paragraphStyle := &docs.ParagraphStyle{}
@@ -740,6 +834,19 @@ func applyFormattingToRange(docsService *docs.Service, docID string, startIndex,
 			requests = append(requests, request)
 		}
 	}
+	return requests
+}
+
+// applyFormattingToRange applies LineFeatures to a specific range in the target document
+func applyFormattingToRange(ctx context.Context, docsService *docs.Service, docID string, startIndex, endIndex int64, features *LineFeatures) error {
+
+	// Pace formatting writes to stay within the API write quota
+	if err := formattingLimiter.wait(ctx); err != nil {
+		return err
+	}
+	logLineFeatures("applying features to range", startIndex, endIndex, features)
+
+	requests := formattingRequests(startIndex, endIndex, features)
 
 	// Execute batch update if we have requests
 	if len(requests) > 0 {
@@ -747,7 +854,7 @@ func applyFormattingToRange(docsService *docs.Service, docID string, startIndex,
 			Requests: requests,
 		}
 
-		_, err := docsService.Documents.BatchUpdate(docID, batchUpdateRequest).Do()
+		_, err := formattingRetryPolicy.batchUpdate(ctx, docsService, docID, batchUpdateRequest)
 		if err != nil {
 			return fmt.Errorf("failed to apply formatting: %v", err)
 		}
@@ -757,26 +864,38 @@ func applyFormattingToRange(docsService *docs.Service, docID string, startIndex,
 }
 
 // syncDocumentFormatting synchronizes formatting from source to target document
-func syncDocumentFormatting(sourceURL, targetURL string, startLoop int) {
+func syncDocumentFormatting(c *clients, sourceURL, targetURL string, startLoop int, reportPath string) {
 	sourceDocID := extractDocumentID(sourceURL)
 	targetDocID := extractDocumentID(targetURL)
 
 	if sourceDocID == "" {
-		log.Fatal("Invalid source Google Docs URL. Please provide a valid document URL.")
+		logFatal("invalid source Google Docs URL, please provide a valid document URL", "url", sourceURL)
 	}
 	if targetDocID == "" {
-		log.Fatal("Invalid target Google Docs URL. Please provide a valid document URL.")
+		logFatal("invalid target Google Docs URL, please provide a valid document URL", "url", targetURL)
 	}
 
-	fmt.Printf("Source Document ID: %s\n", sourceDocID)
-	fmt.Printf("Target Document ID: %s\n", targetDocID)
+	slog.Info("synchronizing document formatting", "source_doc_id", sourceDocID, "target_doc_id", targetDocID)
+
+	var report *SyncReport
+	if reportPath != "" {
+		report = newSyncReport("Sync Format Report", sourceDocID, targetDocID)
+	}
 
-	err := processDualDocuments(sourceDocID, targetDocID, startLoop)
+	err := processDualDocuments(context.Background(), c.Docs, sourceDocID, targetDocID, startLoop, report)
+	if report != nil {
+		report.setError(err)
+		if writeErr := writeSyncReportHTML(reportPath, report); writeErr != nil {
+			slog.Warn("unable to write HTML report", "err", writeErr)
+		} else {
+			slog.Info("HTML report written", "path", reportPath)
+		}
+	}
 	if err != nil {
-		log.Fatalf("Error synchronizing documents: %v", err)
+		logFatal("error synchronizing documents", "err", err)
 	}
 
-	fmt.Println("Document formatting synchronization completed successfully!")
+	slog.Info("document formatting synchronization completed successfully")
 }
 
 // Utility functions for dual-document synchronization
@@ -870,29 +989,15 @@ func extractLineFeatures(element *docs.StructuralElement, textRun *docs.TextRun,
 }
 
 // processDualDocuments implements the main dual-document synchronization algorithm
-func processDualDocuments(sourceDocID, targetDocID string, startLoop int) error {
-	ctx := context.Background()
-
-	// Load credentials
-	credentialsFile := "churchoutline.json"
-	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
-		return fmt.Errorf("churchoutline.json not found. Please follow setup instructions in README.md")
-	}
-
-	// Create Docs service with write permissions
-	docsService, err := docs.NewService(ctx, option.WithCredentialsFile(credentialsFile), option.WithScopes(docs.DocumentsScope))
-	if err != nil {
-		return fmt.Errorf("unable to create Docs service: %v", err)
-	}
-
+func processDualDocuments(ctx context.Context, docsService *docs.Service, sourceDocID, targetDocID string, startLoop int, report *SyncReport) error {
 	// Get source document
-	sourceDoc, err := docsService.Documents.Get(sourceDocID).Do()
+	sourceDoc, err := getDocument(ctx, docsService, sourceDocID)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve source document: %v", err)
 	}
 
 	// Get target document
-	targetDoc, err := docsService.Documents.Get(targetDocID).Do()
+	targetDoc, err := getDocument(ctx, docsService, targetDocID)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve target document: %v", err)
 	}
@@ -902,11 +1007,25 @@ func processDualDocuments(sourceDocID, targetDocID string, startLoop int) error
 	targetCursor := &DocumentCursor{Document: targetDoc, ElementIndex: 0, LineIndex: 0}
 
 	// Process documents
-	return synchronizeDocuments(sourceCursor, targetCursor, targetDocID, docsService, startLoop)
+	if err := synchronizeDocuments(ctx, sourceCursor, targetCursor, targetDocID, docsService, startLoop, report); err != nil {
+		return err
+	}
+
+	// Record the source formatting as the baseline for later reverse syncs
+	baselineLines, err := buildBaselineLines(sourceDoc)
+	if err != nil {
+		return fmt.Errorf("unable to build sync baseline: %v", err)
+	}
+	err = saveSyncBaseline(&SyncBaseline{SourceDocID: sourceDocID, TargetDocID: targetDocID, Lines: baselineLines})
+	if err != nil {
+		return fmt.Errorf("unable to save sync baseline: %v", err)
+	}
+
+	return nil
 }
 
 // synchronizeDocuments performs the actual synchronization between two documents
-func synchronizeDocuments(sourceCursor, targetCursor *DocumentCursor, targetDocID string, docsService *docs.Service, startLoop int) error {
+func synchronizeDocuments(ctx context.Context, sourceCursor, targetCursor *DocumentCursor, targetDocID string, docsService *docs.Service, startLoop int, report *SyncReport) error {
 	sourceLineNum := 0
 	targetLineNum := 0
 	var previousFeatures *LineFeatures
@@ -929,12 +1048,12 @@ func synchronizeDocuments(sourceCursor, targetCursor *DocumentCursor, targetDocI
 	sourceLineNum++
 	sourceKey = generateLineKey(sourceLineInfo.Text)
 	sourceFeatures = extractLineFeatures(sourceLineInfo.Element, sourceLineInfo.TextRun, sourceLineInfo.Text)
-	fmt.Printf("Source Line %d: %s (key: %s)\n", sourceLineNum, sourceLineInfo.Text, sourceKey)
+	slog.Debug("source line", "source_line", sourceLineNum, "text", sourceLineInfo.Text, "key", sourceKey)
 
-	fmt.Println("Starting document synchronization...")
+	slog.Info("starting document synchronization")
 
 	if startLoop > 1 {
-		fmt.Printf("Fast-forwarding to loop %d...\n", startLoop)
+		slog.Info("fast-forwarding", "start_loop", startLoop)
 		for loopID := 1; loopID < startLoop; loopID++ {
 			if shouldAdvanceSourceCursor(lastProcessedWasChinese) {
 				var err error
@@ -980,7 +1099,7 @@ func synchronizeDocuments(sourceCursor, targetCursor *DocumentCursor, targetDocI
 	}
 
 	for loopID := startLoop; ; loopID++ {
-		fmt.Printf("Loop %d, lastProcessedWasChinese: %v\n", loopID, lastProcessedWasChinese)
+		slog.Debug("loop", "loop", loopID, "last_processed_was_chinese", lastProcessedWasChinese)
 		// Only advance source cursor if the last target line processed was Chinese
 		if shouldAdvanceSourceCursor(lastProcessedWasChinese) {
 			// Advance to next English source line (source only has English lines)
@@ -988,7 +1107,7 @@ func synchronizeDocuments(sourceCursor, targetCursor *DocumentCursor, targetDocI
 			sourceLineInfo, err = getNextNonEmptyLine(sourceCursor)
 			if err != nil {
 				if err.Error() == "end of document" {
-					fmt.Printf("Reached end of source document at line %d\n", sourceLineNum)
+					slog.Info("reached end of source document", "source_line", sourceLineNum)
 					break
 				}
 				return fmt.Errorf("error reading source document: %v", err)
@@ -999,14 +1118,14 @@ func synchronizeDocuments(sourceCursor, targetCursor *DocumentCursor, targetDocI
 			sourceKey = generateLineKey(sourceLineInfo.Text)
 			sourceFeatures = extractLineFeatures(sourceLineInfo.Element, sourceLineInfo.TextRun, sourceLineInfo.Text)
 
-			fmt.Printf("Source Line %d: %s (key: %s)\n", sourceLineNum, sourceLineInfo.Text, sourceKey)
+			slog.Debug("source line", "source_line", sourceLineNum, "text", sourceLineInfo.Text, "key", sourceKey)
 		}
 
 		// Always move target cursor to next non-empty line
 		targetLineInfo, err := getNextNonEmptyLine(targetCursor)
 		if err != nil {
 			if err.Error() == "end of document" {
-				fmt.Printf("Reached end of target document at line %d\n", targetLineNum)
+				slog.Info("reached end of target document", "target_line", targetLineNum)
 				break
 			}
 			return fmt.Errorf("error reading target document: %v", err)
@@ -1015,25 +1134,25 @@ func synchronizeDocuments(sourceCursor, targetCursor *DocumentCursor, targetDocI
 
 		// Use matcher to analyze the line and make decisions
 		decision := AnalyzeLineMatch(sourceLineInfo.Text, targetLineInfo.Text, previousFeatures, lastProcessedWasChinese)
-		fmt.Printf("Line Decision: %+v\n", decision)
 
 		if decision.LineType == LineTypeChinese || decision.LineType == LineTypeMixed {
-			fmt.Printf("Target Line %d (Chinese): %s - applying previous formatting\n", targetLineNum, targetLineInfo.Text)
-
 			// Check if previous features contain tabs
 			if previousFeatures != nil && previousFeatures.LeadingTabs > 0 {
 				tabsToAddMap[loopID] = previousFeatures.LeadingTabs
-				fmt.Printf("  Chinese line: will add %d tabs (from previous features)\n", previousFeatures.LeadingTabs)
+				slog.Debug("Chinese line will get tabs from previous features", "loop", loopID, "tabs", previousFeatures.LeadingTabs)
 			}
 
 			// Apply previous line's formatting if available and decision recommends it
 			if decision.ShouldFollowPrevStyle && previousFeatures != nil {
-				err := applyFormattingToRange(docsService, targetDocID, targetLineInfo.Element.StartIndex, targetLineInfo.Element.EndIndex, previousFeatures)
+				logLineDecision(loopID, sourceLineNum, targetLineNum, decision, "apply_previous_format", targetLineInfo.Text)
+				err := applyFormattingToRange(ctx, docsService, targetDocID, targetLineInfo.Element.StartIndex, targetLineInfo.Element.EndIndex, previousFeatures)
 				if err != nil {
-					fmt.Printf("  Warning: Failed to apply formatting: %v\n", err)
-				} else {
-					fmt.Printf("  Applied formatting from previous line\n")
+					slog.Warn("failed to apply formatting", "loop", loopID, "target_line", targetLineNum, "err", err)
 				}
+				report.addChineseLine(loopID, targetLineNum, targetLineInfo.Text, decision, previousFeatures, err)
+			} else {
+				logLineDecision(loopID, sourceLineNum, targetLineNum, decision, "skip_format", targetLineInfo.Text)
+				report.addChineseLine(loopID, targetLineNum, targetLineInfo.Text, decision, nil, nil)
 			}
 
 			// Mark that we processed a Chinese line - this will trigger source advance next iteration
@@ -1041,10 +1160,12 @@ func synchronizeDocuments(sourceCursor, targetCursor *DocumentCursor, targetDocI
 		} else {
 			// Generate key for English target line
 			targetKey := generateLineKey(targetLineInfo.Text)
-			fmt.Printf("Target Line %d (English): %s (key: %s)\n", targetLineNum, targetLineInfo.Text, targetKey)
+			slog.Debug("target line", "target_line", targetLineNum, "text", targetLineInfo.Text, "key", targetKey)
 
 			// Check if keys match with current source line
 			if !decision.LinesMatch {
+				logLineDecision(loopID, sourceLineNum, targetLineNum, decision, "mismatch", targetLineInfo.Text)
+				report.addEnglishLine(loopID, sourceLineNum, sourceLineInfo.Text, targetLineNum, targetLineInfo.Text, decision, nil, nil)
 				return &SyncError{
 					SourceLine: sourceLineNum,
 					TargetLine: targetLineNum,
@@ -1057,16 +1178,16 @@ func synchronizeDocuments(sourceCursor, targetCursor *DocumentCursor, targetDocI
 			// Check if source features contain tabs
 			if sourceFeatures.LeadingTabs > 0 {
 				tabsToAddMap[loopID] = sourceFeatures.LeadingTabs
-				fmt.Printf("  English line: will add %d tabs (from source features)\n", sourceFeatures.LeadingTabs)
+				slog.Debug("English line will get tabs from source features", "loop", loopID, "tabs", sourceFeatures.LeadingTabs)
 			}
 
 			// Keys match - apply source formatting to target
-			err := applyFormattingToRange(docsService, targetDocID, targetLineInfo.Element.StartIndex, targetLineInfo.Element.EndIndex, sourceFeatures)
+			logLineDecision(loopID, sourceLineNum, targetLineNum, decision, "apply_source_format", targetLineInfo.Text)
+			err := applyFormattingToRange(ctx, docsService, targetDocID, targetLineInfo.Element.StartIndex, targetLineInfo.Element.EndIndex, sourceFeatures)
 			if err != nil {
-				fmt.Printf("  Warning: Failed to apply formatting: %v\n", err)
-			} else {
-				fmt.Printf("  Keys match - applied source formatting\n")
+				slog.Warn("failed to apply formatting", "loop", loopID, "target_line", targetLineNum, "err", err)
 			}
+			report.addEnglishLine(loopID, sourceLineNum, sourceLineInfo.Text, targetLineNum, targetLineInfo.Text, decision, sourceFeatures, err)
 			previousFeatures = sourceFeatures
 
 			// Mark that we processed an English line - source cursor stays on same line
@@ -1074,26 +1195,15 @@ func synchronizeDocuments(sourceCursor, targetCursor *DocumentCursor, targetDocI
 		}
 	}
 
-	// Print the tabs to add hashmap at the end
-	fmt.Println("\n=== Tabs to Add Summary ===")
-	if len(tabsToAddMap) == 0 {
-		fmt.Println("No tabs to add for any loop iteration")
-	} else {
-		fmt.Printf("Total loop iterations with tabs: %d\n", len(tabsToAddMap))
-		for loopID := 1; loopID <= len(tabsToAddMap)+10; loopID++ {
-			if tabs, exists := tabsToAddMap[loopID]; exists {
-				fmt.Printf("Loop %d: %d tab(s)\n", loopID, tabs)
-			}
-		}
-	}
-	fmt.Println("==========================")
+	// Summarize the tabs to add at the end
+	slog.Info("tabs to add", "loop_iterations", len(tabsToAddMap))
 
 	// Now insert tabs at the beginning of lines in target document
 	if len(tabsToAddMap) > 0 {
-		fmt.Println("Inserting tabs into target document...")
+		slog.Info("inserting tabs into target document")
 
 		// Re-fetch the target document to get fresh indices
-		targetDoc, err := docsService.Documents.Get(targetDocID).Do()
+		targetDoc, err := getDocument(ctx, docsService, targetDocID)
 		if err != nil {
 			return fmt.Errorf("unable to re-fetch target document: %v", err)
 		}
@@ -1136,7 +1246,7 @@ func synchronizeDocuments(sourceCursor, targetCursor *DocumentCursor, targetDocI
 						},
 					}
 					insertRequests = append(insertRequests, insertRequest)
-					fmt.Printf("  Preparing to insert %d tab(s) at loop %d (index %d)\n", numTabs, loopID, startIndex)
+					slog.Debug("preparing to insert tabs", "loop", loopID, "tabs", numTabs, "index", startIndex)
 				}
 			}
 		}
@@ -1147,12 +1257,12 @@ func synchronizeDocuments(sourceCursor, targetCursor *DocumentCursor, targetDocI
 				Requests: insertRequests,
 			}
 
-			_, err = docsService.Documents.BatchUpdate(targetDocID, batchUpdateRequest).Do()
+			_, err = batchUpdateDocument(ctx, docsService, targetDocID, batchUpdateRequest)
 			if err != nil {
 				return fmt.Errorf("failed to insert tabs: %v", err)
 			}
 
-			fmt.Printf("Successfully inserted tabs at %d locations\n", len(insertRequests))
+			slog.Info("inserted tabs", "locations", len(insertRequests))
 		}
 	}
 
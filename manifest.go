@@ -0,0 +1,170 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// runsDir holds one directory per e2e run with its manifest and cached translation
+const runsDir = "runs"
+
+// e2e steps in the order they run; a resumed run skips the ones already recorded as completed
+const (
+	StepCreateOutput        = "create_output"
+	StepGrantServiceAccount = "grant_service_account"
+	StepShareOutput         = "share_output"
+	StepTranslate           = "translate"
+	StepWriteOutput         = "write_output"
+	StepReview              = "review"
+	StepSyncFormat          = "sync_format"
+)
+
+// e2eSteps lists the steps in run order
+var e2eSteps = []string{
+	StepCreateOutput,
+	StepGrantServiceAccount,
+	StepShareOutput,
+	StepTranslate,
+	StepWriteOutput,
+	StepReview,
+	StepSyncFormat,
+}
+
+// runManifest records the progress of an e2e run so that it can be resumed
+type runManifest struct {
+	RunID           string    `json:"run_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	Input           string    `json:"input"`
+	InputFile       string    `json:"input_file,omitempty"` // local file imported as the input doc
+	InputDocID      string    `json:"input_doc_id"`
+	InputRevision   string    `json:"input_revision"`
+	OutputMode      string    `json:"output_mode"`
+	OutputName      string    `json:"output_name,omitempty"`
+	OutputDocID     string    `json:"output_doc_id,omitempty"`
+	OutputURL       string    `json:"output_url,omitempty"`
+	PromptHash      string    `json:"prompt_hash"`
+	TranslationFile string    `json:"translation_file,omitempty"`
+	ReviewReport    string    `json:"review_report,omitempty"`
+	SourceSnapshot  string    `json:"source_snapshot,omitempty"`
+	CompletedSteps  []string  `json:"completed_steps"`
+}
+
+// newRunID returns a sortable, unique run ID such as 20240310-093000-1a2b3c
+func newRunID(now time.Time) (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(b), nil
+}
+
+// runIDPattern matches the IDs made by newRunID
+var runIDPattern = regexp.MustCompile(`^\d{8}-\d{6}-[0-9a-f]{6}$`)
+
+// validateRunID rejects a run ID that newRunID could not have made, such as "../x", so that
+// it can't name a path outside runsDir
+func validateRunID(runID string) error {
+	if !runIDPattern.MatchString(runID) {
+		return fmt.Errorf("invalid run ID %q (want the form 20240310-093000-1a2b3c)", runID)
+	}
+	return nil
+}
+
+// runDir returns the directory of a run
+func runDir(runID string) string {
+	return filepath.Join(runsDir, runID)
+}
+
+// manifestPath returns the manifest file of a run
+func manifestPath(runID string) string {
+	return filepath.Join(runDir(runID), "manifest.json")
+}
+
+// promptHash identifies the prompts and model a translation was produced with
+func promptHash(systemPrompt, prefixPrompt, model string) string {
+	return hashParts(systemPrompt, prefixPrompt, model)
+}
+
+// hashParts returns a hex SHA-256 over the parts, separated so that moving text between
+// parts changes the hash
+func hashParts(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadRunManifest reads the manifest of an earlier run
+func loadRunManifest(runID string) (*runManifest, error) {
+	if err := validateRunID(runID); err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(manifestPath(runID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no run %q found in %s", runID, runsDir)
+		}
+		return nil, err
+	}
+	var m runManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("unable to parse run manifest: %v", err)
+	}
+	// Later writes go to the directory named by the manifest's own ID
+	if m.RunID != runID {
+		return nil, fmt.Errorf("run manifest in %s belongs to run %q", runDir(runID), m.RunID)
+	}
+	return &m, nil
+}
+
+// saveRunManifest writes the manifest, replacing the previous one atomically
+func saveRunManifest(m *runManifest) error {
+	if err := os.MkdirAll(runDir(m.RunID), 0755); err != nil {
+		return err
+	}
+	m.UpdatedAt = time.Now()
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := manifestPath(m.RunID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, manifestPath(m.RunID))
+}
+
+// isCompleted reports whether a step has already run
+func (m *runManifest) isCompleted(step string) bool {
+	return slices.Contains(m.CompletedSteps, step)
+}
+
+// markCompleted records a step as completed
+func (m *runManifest) markCompleted(step string) {
+	if !m.isCompleted(step) {
+		m.CompletedSteps = append(m.CompletedSteps, step)
+	}
+}
+
+// invalidateFrom forgets step and every step after it so that they run again
+func (m *runManifest) invalidateFrom(step string) {
+	start := slices.Index(e2eSteps, step)
+	if start < 0 {
+		return
+	}
+	later := e2eSteps[start:]
+	m.CompletedSteps = slices.DeleteFunc(m.CompletedSteps, func(s string) bool {
+		return slices.Contains(later, s)
+	})
+}
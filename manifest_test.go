@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRunManifestSaveAndLoad(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	m := &runManifest{RunID: "20240310-093000-1a2b3c", InputDocID: "in", OutputDocID: "out", PromptHash: "abc"}
+	m.markCompleted(StepCreateOutput)
+	m.markCompleted(StepCreateOutput)
+	if err := saveRunManifest(m); err != nil {
+		t.Fatalf("saveRunManifest() error = %v", err)
+	}
+
+	loaded, err := loadRunManifest(m.RunID)
+	if err != nil {
+		t.Fatalf("loadRunManifest() error = %v", err)
+	}
+	if loaded.OutputDocID != "out" || !slices.Equal(loaded.CompletedSteps, []string{StepCreateOutput}) {
+		t.Errorf("loaded manifest = %+v", loaded)
+	}
+
+	if _, err := loadRunManifest("20240310-093000-ffffff"); err == nil {
+		t.Error("loadRunManifest() of an unknown run should fail")
+	}
+
+	// A manifest copied into another run's directory is not loaded under that run's ID
+	if err := os.Rename(runDir(m.RunID), runDir("20240310-093000-000000")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadRunManifest("20240310-093000-000000"); err == nil {
+		t.Error("loadRunManifest() accepted a manifest of another run")
+	}
+}
+
+func TestRunManifestInvalidateFrom(t *testing.T) {
+	m := &runManifest{CompletedSteps: []string{StepCreateOutput, StepGrantServiceAccount, StepShareOutput, StepTranslate, StepWriteOutput}}
+	m.invalidateFrom(StepTranslate)
+
+	expected := []string{StepCreateOutput, StepGrantServiceAccount, StepShareOutput}
+	if !slices.Equal(m.CompletedSteps, expected) {
+		t.Errorf("CompletedSteps = %v, want %v", m.CompletedSteps, expected)
+	}
+	if m.isCompleted(StepWriteOutput) {
+		t.Error("write_output should run again")
+	}
+}
+
+func TestPromptHash(t *testing.T) {
+	base := promptHash("system", "prefix", "grok-4")
+	if base != promptHash("system", "prefix", "grok-4") {
+		t.Error("promptHash() is not deterministic")
+	}
+	if base == promptHash("system", "prefix", "grok-3") {
+		t.Error("promptHash() ignores the model")
+	}
+	if promptHash("ab", "c", "m") == promptHash("a", "bc", "m") {
+		t.Error("promptHash() does not separate its parts")
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	id, err := newRunID(time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("newRunID() error = %v", err)
+	}
+	if !regexp.MustCompile(`^20240310-093000-[0-9a-f]{6}$`).MatchString(id) {
+		t.Errorf("newRunID() = %q", id)
+	}
+}
+
+func TestValidateRunID(t *testing.T) {
+	id, err := newRunID(time.Now())
+	if err != nil {
+		t.Fatalf("newRunID() error = %v", err)
+	}
+	tests := []struct {
+		id      string
+		wantErr bool
+	}{
+		{id, false},
+		{"20240310-093000-1a2b3c", false},
+		{"", true},
+		{"../../x", true},
+		{"20240310-093000-1a2b3c/../../x", true},
+		{`..\20240310-093000-1a2b3c`, true},
+		{"20240310-093000-1A2B3C", true},
+	}
+	for _, tt := range tests {
+		if err := validateRunID(tt.id); (err != nil) != tt.wantErr {
+			t.Errorf("validateRunID(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+		}
+	}
+	if _, err := loadRunManifest("../../x"); err == nil {
+		t.Error("loadRunManifest() accepted a path outside the runs directory")
+	}
+}
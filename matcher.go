@@ -14,6 +14,22 @@ const (
 	LineTypeEmpty
 )
 
+// String returns the display name of a line type
+func (t LineType) String() string {
+	switch t {
+	case LineTypeEnglish:
+		return "English"
+	case LineTypeChinese:
+		return "Chinese"
+	case LineTypeMixed:
+		return "Mixed"
+	case LineTypeEmpty:
+		return "Empty"
+	default:
+		return "Unknown"
+	}
+}
+
 // MatchDecision contains all decisions about how to handle a line
 type MatchDecision struct {
 	ShouldAddEmptyLine    bool
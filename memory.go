@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// defaultTranslationMemoryFile is read by e2e when application.yaml doesn't name a translation memory
+const defaultTranslationMemoryFile = "translation_memory.json"
+
+// tmNearThreshold is the minimum key similarity for a near match
+const tmNearThreshold = 0.9
+
+// digitPattern matches the numbers a near match must share, such as dates and hymn numbers
+var digitPattern = regexp.MustCompile(`[0-9]+`)
+
+// tmEntry is an approved translation of one English line
+type tmEntry struct {
+	English     string    `json:"en"`
+	Chinese     string    `json:"zh"`
+	SourceDocID string    `json:"source_doc_id,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// translationMemory maps English line keys (generateLineKey) to approved translations
+type translationMemory struct {
+	Entries map[string]*tmEntry `json:"entries"`
+}
+
+// tmMatch is a translation memory entry found for a line; Exact is false for near matches
+type tmMatch struct {
+	Entry      *tmEntry
+	Exact      bool
+	Similarity float64
+}
+
+// tmLine is one line of the input text and the memory entry that translates it, if any
+type tmLine struct {
+	Text  string
+	Match *tmMatch
+}
+
+// loadTranslationMemory reads the translation memory; a missing file yields an empty memory
+func loadTranslationMemory(path string) (*translationMemory, error) {
+	tm := &translationMemory{Entries: make(map[string]*tmEntry)}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return tm, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, tm); err != nil {
+		return nil, fmt.Errorf("unable to parse translation memory %s: %v", path, err)
+	}
+	if tm.Entries == nil {
+		tm.Entries = make(map[string]*tmEntry)
+	}
+	return tm, nil
+}
+
+// saveTranslationMemory writes the translation memory, replacing the previous file atomically
+func saveTranslationMemory(path string, tm *translationMemory) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	b, err := json.MarshalIndent(tm, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// upsert stores an approved translation and reports whether the memory changed
+func (tm *translationMemory) upsert(english, chinese, sourceDocID string, now time.Time) bool {
+	key := generateLineKey(english)
+	chinese = strings.TrimSpace(chinese)
+	if key == "" || chinese == "" {
+		return false
+	}
+	if existing, ok := tm.Entries[key]; ok && existing.Chinese == chinese {
+		return false
+	}
+	tm.Entries[key] = &tmEntry{English: strings.TrimSpace(english), Chinese: chinese, SourceDocID: sourceDocID, UpdatedAt: now}
+	return true
+}
+
+// lookup finds the translation of an English line: an exact key match, or else the most similar
+// entry at or above tmNearThreshold with the same numbers. Lines that differ only in a date or a
+// hymn number are near in spelling but need their own translation.
+func (tm *translationMemory) lookup(english string) *tmMatch {
+	key := generateLineKey(english)
+	if key == "" {
+		return nil
+	}
+	if entry, ok := tm.Entries[key]; ok {
+		return &tmMatch{Entry: entry, Exact: true, Similarity: 1}
+	}
+
+	target := []rune(nearKey(key))
+	digits := digitSequences(english)
+	var best *tmMatch
+	for entryKey, entry := range tm.Entries {
+		if !slices.Equal(digits, digitSequences(entry.English)) {
+			continue
+		}
+		candidate := []rune(nearKey(entryKey))
+		longer := max(len(target), len(candidate))
+		// Skip candidates whose length alone rules out the threshold
+		if longer == 0 || float64(abs(len(target)-len(candidate)))/float64(longer) > 1-tmNearThreshold {
+			continue
+		}
+		similarity := 1 - float64(levenshtein(target, candidate))/float64(longer)
+		if similarity >= tmNearThreshold && (best == nil || similarity > best.Similarity ||
+			(similarity == best.Similarity && entry.UpdatedAt.After(best.Entry.UpdatedAt))) {
+			best = &tmMatch{Entry: entry, Similarity: similarity}
+		}
+	}
+	return best
+}
+
+// nearKey lowercases a line key and drops punctuation so near matches ignore case and punctuation
+func nearKey(key string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(key) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// digitSequences returns the runs of digits in a line, in order
+func digitSequences(text string) []string {
+	return digitPattern.FindAllString(text, -1)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// levenshtein returns the edit distance between two rune slices
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// prefillFromMemory looks up every line of the input text in the translation memory and returns
+// the lines with their matches plus the text of the lines the LLM still has to translate.
+// Lines without English are kept as they are and never sent to the LLM.
+func prefillFromMemory(inputText string, tm *translationMemory) ([]tmLine, string) {
+	var lines []tmLine
+	var unseen []string
+	for _, raw := range strings.Split(strings.ReplaceAll(inputText, "\r\n", "\n"), "\n") {
+		line := tmLine{Text: strings.TrimSpace(raw)}
+		if generateLineKey(line.Text) != "" {
+			line.Match = tm.lookup(line.Text)
+			if line.Match == nil {
+				unseen = append(unseen, line.Text)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines, strings.Join(unseen, "\n")
+}
+
+// mergeTranslation rebuilds the bilingual text from the prefilled lines and the LLM's translation
+// of the unseen lines, in input order. It returns the English lines the LLM output didn't cover.
+func mergeTranslation(lines []tmLine, llmOutput string) (string, []string) {
+	// Queue the LLM's translations per key so that repeated lines are consumed in order
+	translated := make(map[string][]string)
+	for _, pair := range parseTranslationPairs(llmOutput) {
+		key := generateLineKey(pair.English)
+		translated[key] = append(translated[key], pair.Chinese)
+	}
+
+	var out []string
+	var missing []string
+	for _, line := range lines {
+		key := generateLineKey(line.Text)
+		switch {
+		case key == "":
+			out = append(out, line.Text)
+		case line.Match != nil:
+			out = append(out, line.Text, line.Match.Entry.Chinese)
+		case len(translated[key]) > 0 && translated[key][0] != "":
+			out = append(out, line.Text, translated[key][0])
+			translated[key] = translated[key][1:]
+		default:
+			out = append(out, line.Text)
+			missing = append(missing, line.Text)
+		}
+	}
+	return strings.Join(out, "\n"), missing
+}
+
+// countReused returns how many lines were translated from memory, split into exact and near matches
+func countReused(lines []tmLine) (exact, near int) {
+	for _, line := range lines {
+		if line.Match == nil {
+			continue
+		}
+		if line.Match.Exact {
+			exact++
+		} else {
+			near++
+		}
+	}
+	return exact, near
+}
+
+// buildReviewReport lays out the translated pairs for the reviewer and marks the ones reused
+// from the translation memory
+func buildReviewReport(inputDocID, outputDocID, translation string, lines []tmLine) *SyncReport {
+	reused := make(map[string]*tmMatch)
+	for _, line := range lines {
+		if line.Match != nil {
+			reused[generateLineKey(line.Text)] = line.Match
+		}
+	}
+
+	report := newSyncReport("Translation review", inputDocID, outputDocID)
+	for i, pair := range parseTranslationPairs(translation) {
+		row := &SyncReportRow{SourceLine: i + 1, SourceText: pair.English, ChineseText: pair.Chinese}
+		if match := reused[generateLineKey(pair.English)]; match != nil {
+			if match.Exact {
+				row.Reused = "Translation memory (exact)"
+			} else {
+				row.Reused = fmt.Sprintf("Translation memory (near, %.0f%%): %s", match.Similarity*100, match.Entry.English)
+			}
+		}
+		if generateLineKey(pair.English) != "" && pair.Chinese == "" {
+			row.Warning = "No translation"
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	return report
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testMemory() *translationMemory {
+	tm := &translationMemory{Entries: make(map[string]*tmEntry)}
+	now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	tm.upsert("The grace of the Lord Jesus Christ be with you all.", "愿主耶稣基督的恩惠常与你们众人同在。", "doc1", now)
+	tm.upsert("Call to Worship", "宣召", "doc1", now)
+	tm.upsert("Sunday, March 10, 2024", "2024年3月10日，主日", "doc1", now)
+	tm.upsert("Hymn 123: Amazing Grace", "诗歌123：奇异恩典", "doc1", now)
+	return tm
+}
+
+func TestTranslationMemoryLookup(t *testing.T) {
+	tm := testMemory()
+
+	tests := []struct {
+		name    string
+		english string
+		found   bool
+		exact   bool
+		chinese string
+	}{
+		{"Exact", "The grace of the Lord Jesus Christ be with you all.", true, true, "愿主耶稣基督的恩惠常与你们众人同在。"},
+		{"Whitespace ignored", "  Call to  Worship", true, true, "宣召"},
+		{"Near", "The grace of the Lord Jesus Christ be with you all!", true, false, "愿主耶稣基督的恩惠常与你们众人同在。"},
+		{"Near with a small edit", "The grace of our Lord Jesus Christ be with you all.", true, false, "愿主耶稣基督的恩惠常与你们众人同在。"},
+		{"Short lines need to match closely", "Call to Prayer", false, false, ""},
+		{"Date with another day", "Sunday, March 17, 2024", false, false, ""},
+		{"Hymn with another number", "Hymn 124: Amazing Grace", false, false, ""},
+		{"Same numbers", "Sunday March 10, 2024", true, false, "2024年3月10日，主日"},
+		{"Unseen", "Sermon: Walking by Faith", false, false, ""},
+		{"No English", "——", false, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := tm.lookup(tt.english)
+			if (match != nil) != tt.found {
+				t.Fatalf("lookup(%q) = %+v, found want %v", tt.english, match, tt.found)
+			}
+			if match == nil {
+				return
+			}
+			if match.Exact != tt.exact || match.Entry.Chinese != tt.chinese {
+				t.Errorf("lookup(%q) = exact %v %q, want exact %v %q", tt.english, match.Exact, match.Entry.Chinese, tt.exact, tt.chinese)
+			}
+		})
+	}
+}
+
+func TestTranslationMemoryUpsertAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tm", "translation_memory.json")
+	tm, err := loadTranslationMemory(path)
+	if err != nil || len(tm.Entries) != 0 {
+		t.Fatalf("loadTranslationMemory() of a missing file = %+v, %v", tm, err)
+	}
+
+	now := time.Now()
+	if !tm.upsert("Benediction", "祝福", "doc1", now) {
+		t.Error("upsert() of a new line should change the memory")
+	}
+	if tm.upsert("Benediction", "祝福", "doc2", now) {
+		t.Error("upsert() of the same translation should not change the memory")
+	}
+	if !tm.upsert("Benediction", "祝祷", "doc2", now) {
+		t.Error("upsert() of a corrected translation should change the memory")
+	}
+	if tm.upsert("Benediction", " ", "doc2", now) || tm.upsert("——", "破折号", "doc2", now) {
+		t.Error("upsert() should ignore lines without a translation or without English")
+	}
+
+	if err := saveTranslationMemory(path, tm); err != nil {
+		t.Fatalf("saveTranslationMemory() error = %v", err)
+	}
+	loaded, err := loadTranslationMemory(path)
+	if err != nil {
+		t.Fatalf("loadTranslationMemory() error = %v", err)
+	}
+	entry := loaded.Entries[generateLineKey("Benediction")]
+	if entry == nil || entry.Chinese != "祝祷" || entry.SourceDocID != "doc2" {
+		t.Errorf("loaded entry = %+v", entry)
+	}
+}
+
+func TestPrefillAndMergeTranslation(t *testing.T) {
+	tm := testMemory()
+	input := "Call to Worship\n\nSermon: Walking by Faith\nWe walk by faith.\n——\nThe grace of the Lord Jesus Christ be with you all!"
+
+	lines, unseen := prefillFromMemory(input, tm)
+	if unseen != "Sermon: Walking by Faith\nWe walk by faith." {
+		t.Errorf("unseen = %q", unseen)
+	}
+	if exact, near := countReused(lines); exact != 1 || near != 1 {
+		t.Errorf("countReused() = %d, %d, want 1, 1", exact, near)
+	}
+
+	llmOutput := "Sermon: Walking by Faith\n讲道：凭信心行走\n\nWe walk by faith.\n"
+	merged, missing := mergeTranslation(lines, llmOutput)
+	expected := strings.Join([]string{
+		"Call to Worship", "宣召",
+		"",
+		"Sermon: Walking by Faith", "讲道：凭信心行走",
+		"We walk by faith.",
+		"——",
+		"The grace of the Lord Jesus Christ be with you all!", "愿主耶稣基督的恩惠常与你们众人同在。",
+	}, "\n")
+	if merged != expected {
+		t.Errorf("mergeTranslation() =\n%s\nwant\n%s", merged, expected)
+	}
+	if len(missing) != 1 || missing[0] != "We walk by faith." {
+		t.Errorf("missing = %v, want [We walk by faith.]", missing)
+	}
+
+	report := buildReviewReport("in", "out", merged, lines)
+	var reused, warnings int
+	for _, row := range report.Rows {
+		if row.Reused != "" {
+			reused++
+		}
+		if row.Warning != "" {
+			warnings++
+		}
+	}
+	if reused != 2 || warnings != 1 {
+		t.Errorf("review report has %d reused and %d warning rows, want 2 and 1", reused, warnings)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"恩惠", "恩典", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.expected {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/drive/v3"
+)
+
+// oauthLoginTimeout bounds how long the loopback listener waits for the browser redirect
+const oauthLoginTimeout = 5 * time.Minute
+
+// googleRevokeURL is Google's token revocation endpoint; replaceable in tests
+var googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+
+// loadOAuthConfig reads an OAuth client config file for Drive access
+func loadOAuthConfig(path string) (*oauth2.Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read oauth client file: %w", err)
+	}
+	config, err := google.ConfigFromJSON(b, drive.DriveScope)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse oauth client file to config: %w", err)
+	}
+	return config, nil
+}
+
+// getOAuthClient returns an HTTP client for the cached token, logging in first when there is none.
+// Tokens refreshed by oauth2 are written back to tokenPath.
+func getOAuthClient(ctx context.Context, config *oauth2.Config, tokenPath string) (*http.Client, error) {
+	tok, err := tokenFromFile(tokenPath)
+	if err != nil {
+		slog.Info("no cached OAuth token, starting browser login", "token", tokenPath)
+		tok, err = loopbackLogin(ctx, config, printAuthURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := saveToken(tokenPath, tok); err != nil {
+			return nil, err
+		}
+	}
+	return oauth2.NewClient(ctx, newPersistingTokenSource(ctx, config, tok, tokenPath)), nil
+}
+
+// printAuthURL asks the user to open the consent page
+func printAuthURL(authURL string) error {
+	fmt.Printf("Open the following link in your browser to authorize access:\n%v\n", authURL)
+	return nil
+}
+
+// randomState returns an unguessable OAuth state value
+func randomState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// loopbackResult is what the redirect handler received from the browser
+type loopbackResult struct {
+	code string
+	err  error
+}
+
+// loopbackLogin runs the installed-app OAuth flow: it listens on a random loopback port, sends
+// the user to the consent page with a random state and a PKCE challenge, and exchanges the
+// code delivered to the redirect for a token
+func loopbackLogin(ctx context.Context, config *oauth2.Config, openURL func(string) error) (*oauth2.Token, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("unable to start loopback listener: %w", err)
+	}
+	defer listener.Close()
+
+	cfg := *config
+	cfg.RedirectURL = fmt.Sprintf("http://%s/", listener.Addr().String())
+
+	state, err := randomState()
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate oauth state: %w", err)
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	results := make(chan loopbackResult, 1)
+	var once sync.Once
+	deliver := func(r loopbackResult) {
+		once.Do(func() { results <- r })
+	}
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		switch {
+		case query.Get("state") != state:
+			http.Error(w, "Invalid state parameter.", http.StatusBadRequest)
+			deliver(loopbackResult{err: errors.New("oauth redirect carried an unexpected state")})
+		case query.Get("error") != "":
+			http.Error(w, "Authorization was not granted. You can close this window.", http.StatusForbidden)
+			deliver(loopbackResult{err: fmt.Errorf("authorization failed: %s", query.Get("error"))})
+		case query.Get("code") == "":
+			http.Error(w, "Missing authorization code.", http.StatusBadRequest)
+			deliver(loopbackResult{err: errors.New("oauth redirect carried no authorization code")})
+		default:
+			fmt.Fprintln(w, "Authorization complete. You can close this window and return to the terminal.")
+			deliver(loopbackResult{code: query.Get("code")})
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authURL := cfg.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
+	if err := openURL(authURL); err != nil {
+		return nil, fmt.Errorf("unable to open authorization URL: %w", err)
+	}
+
+	timer := time.NewTimer(oauthLoginTimeout)
+	defer timer.Stop()
+	var result loopbackResult
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, errors.New("timed out waiting for the browser authorization")
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	tok, err := cfg.Exchange(ctx, result.code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("unable to exchange authorization code: %w", err)
+	}
+	return tok, nil
+}
+
+// persistingTokenSource saves the token to disk whenever oauth2 refreshes or rotates it
+type persistingTokenSource struct {
+	mu   sync.Mutex
+	base oauth2.TokenSource
+	path string
+	last *oauth2.Token
+}
+
+// newPersistingTokenSource wraps config's refreshing token source for tok
+func newPersistingTokenSource(ctx context.Context, config *oauth2.Config, tok *oauth2.Token, path string) oauth2.TokenSource {
+	return &persistingTokenSource{base: config.TokenSource(ctx, tok), path: path, last: tok}
+}
+
+// Token returns a valid token, writing it to disk when it differs from the last one seen
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil || tok.AccessToken != s.last.AccessToken || tok.RefreshToken != s.last.RefreshToken {
+		if err := saveToken(s.path, tok); err != nil {
+			slog.Warn("unable to persist refreshed oauth token", "token", s.path, "err", err)
+		} else {
+			slog.Debug("persisted refreshed oauth token", "token", s.path, "expiry", tok.Expiry)
+		}
+		s.last = tok
+	}
+	return tok, nil
+}
+
+func tokenFromFile(file string) (*oauth2.Token, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tok := &oauth2.Token{}
+	err = json.NewDecoder(f).Decode(tok)
+	return tok, err
+}
+
+func saveToken(path string, token *oauth2.Token) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to cache oauth token: %w", err)
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(token); err != nil {
+		return fmt.Errorf("unable to cache oauth token: %w", err)
+	}
+	return nil
+}
+
+// revokeToken asks Google to revoke the refresh token (or the access token when there is none)
+func revokeToken(ctx context.Context, tok *oauth2.Token) error {
+	value := tok.RefreshToken
+	if value == "" {
+		value = tok.AccessToken
+	}
+	if value == "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, googleRevokeURL, strings.NewReader(url.Values{"token": {value}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("revocation returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// runAuthCommand implements the auth login/status/logout command group
+func runAuthCommand(auth authSettings, args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: go run main.go auth login|status|logout")
+		os.Exit(1)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "login":
+		config, err := loadOAuthConfig(auth.OAuthClientFile)
+		if err != nil {
+			logFatal("unable to load oauth client", "err", err)
+		}
+		tok, err := loopbackLogin(ctx, config, printAuthURL)
+		if err != nil {
+			logFatal("login failed", "err", err)
+		}
+		if err := saveToken(auth.TokenFile, tok); err != nil {
+			logFatal("unable to save token", "err", err)
+		}
+		slog.Info("login successful", "token", auth.TokenFile)
+	case "status":
+		printAuthStatus(auth)
+	case "logout":
+		tok, err := tokenFromFile(auth.TokenFile)
+		if err != nil {
+			if os.IsNotExist(err) {
+				fmt.Println("Not logged in.")
+				return
+			}
+			logFatal("unable to read token", "token", auth.TokenFile, "err", err)
+		}
+		if err := revokeToken(ctx, tok); err != nil {
+			slog.Warn("unable to revoke token, removing local copy anyway", "err", err)
+		}
+		if err := os.Remove(auth.TokenFile); err != nil {
+			logFatal("unable to remove token", "token", auth.TokenFile, "err", err)
+		}
+		fmt.Printf("Logged out; removed %s\n", auth.TokenFile)
+	default:
+		fmt.Printf("Unknown auth command: %s\n", args[0])
+		fmt.Println("Usage: go run main.go auth login|status|logout")
+		os.Exit(1)
+	}
+}
+
+// printAuthStatus reports which credentials are configured and the state of the OAuth token
+func printAuthStatus(auth authSettings) {
+	fmt.Println("=== Auth Status ===")
+	if auth.usesApplicationDefaultCredentials() {
+		fmt.Println("Docs API:     application-default credentials")
+	} else if email, err := auth.serviceAccountEmail(); err == nil {
+		fmt.Printf("Docs API:     service account %s (%s)\n", email, auth.CredentialsFile)
+	} else {
+		fmt.Printf("Docs API:     %s (unreadable: %v)\n", auth.CredentialsFile, err)
+	}
+	fmt.Printf("OAuth client: %s (present: %t)\n", auth.OAuthClientFile, fileExists(auth.OAuthClientFile))
+
+	tok, err := tokenFromFile(auth.TokenFile)
+	if err != nil {
+		fmt.Printf("OAuth token:  %s (not logged in)\n", auth.TokenFile)
+	} else {
+		fmt.Printf("OAuth token:  %s\n", auth.TokenFile)
+		if !tok.Expiry.IsZero() {
+			fmt.Printf("  Expires:       %s (valid: %t)\n", tok.Expiry.Format(time.RFC3339), tok.Valid())
+		}
+		fmt.Printf("  Refresh token: %t\n", tok.RefreshToken != "")
+	}
+
+	if auth.XAIKey != "" {
+		fmt.Println("xAI key:      XAI_API_KEY")
+	} else {
+		fmt.Printf("xAI key:      %s (present: %t)\n", auth.XAIKeyFile, fileExists(auth.XAIKeyFile))
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// fakeOAuthServer is a minimal authorization server that records the PKCE challenge and
+// issues tokens from its token endpoint
+type fakeOAuthServer struct {
+	mu        sync.Mutex
+	challenge string
+	refreshes int
+}
+
+func (f *fakeOAuthServer) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm: %v", err)
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if base64.RawURLEncoding.EncodeToString(sum[:]) != f.challenge {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			if r.Form.Get("code") != "good-code" {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"access_token": "access-1", "refresh_token": "refresh-1", "token_type": "Bearer", "expires_in": 3600})
+		case "refresh_token":
+			f.refreshes++
+			json.NewEncoder(w).Encode(map[string]any{
+				"access_token":  fmt.Sprintf("access-%d", f.refreshes+1),
+				"refresh_token": fmt.Sprintf("refresh-%d", f.refreshes+1),
+				"token_type":    "Bearer",
+				"expires_in":    3600,
+			})
+		default:
+			http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+		}
+	})
+	return mux
+}
+
+func newFakeOAuthConfig(serverURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   serverURL + "/auth",
+			TokenURL:  serverURL + "/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+}
+
+func TestLoopbackLogin(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		state     func(sent string) string
+		errParam  string
+		wantToken string
+		wantErr   bool
+	}{
+		{"Successful login", "good-code", func(s string) string { return s }, "", "access-1", false},
+		{"State mismatch", "good-code", func(string) string { return "forged" }, "", "", true},
+		{"Consent denied", "", func(s string) string { return s }, "access_denied", "", true},
+		{"Rejected code", "bad-code", func(s string) string { return s }, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeOAuthServer{}
+			server := httptest.NewServer(fake.handler(t))
+			defer server.Close()
+
+			// The "browser" follows the consent URL straight to the loopback redirect
+			browser := func(authURL string) error {
+				u, err := url.Parse(authURL)
+				if err != nil {
+					return err
+				}
+				q := u.Query()
+				if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+					t.Errorf("auth URL is missing a PKCE challenge: %s", authURL)
+				}
+				if q.Get("state") == "" || q.Get("state") == "state-token" {
+					t.Errorf("auth URL has a predictable state: %q", q.Get("state"))
+				}
+				fake.mu.Lock()
+				fake.challenge = q.Get("code_challenge")
+				fake.mu.Unlock()
+
+				redirect := url.Values{"state": {tt.state(q.Get("state"))}}
+				if tt.code != "" {
+					redirect.Set("code", tt.code)
+				}
+				if tt.errParam != "" {
+					redirect.Set("error", tt.errParam)
+				}
+				go func() {
+					resp, err := http.Get(q.Get("redirect_uri") + "?" + redirect.Encode())
+					if err == nil {
+						resp.Body.Close()
+					}
+				}()
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			tok, err := loopbackLogin(ctx, newFakeOAuthConfig(server.URL), browser)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loopbackLogin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tok.AccessToken != tt.wantToken {
+				t.Errorf("loopbackLogin() access token = %q, want %q", tok.AccessToken, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestPersistingTokenSource(t *testing.T) {
+	fake := &fakeOAuthServer{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "token.json")
+	expired := &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1", Expiry: time.Now().Add(-time.Hour)}
+	if err := saveToken(path, expired); err != nil {
+		t.Fatalf("saveToken() error = %v", err)
+	}
+
+	ts := newPersistingTokenSource(context.Background(), newFakeOAuthConfig(server.URL), expired, path)
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if tok.AccessToken != "access-2" {
+		t.Errorf("Token() access token = %q, want %q", tok.AccessToken, "access-2")
+	}
+
+	saved, err := tokenFromFile(path)
+	if err != nil {
+		t.Fatalf("tokenFromFile() error = %v", err)
+	}
+	if saved.AccessToken != "access-2" || saved.RefreshToken != "refresh-2" {
+		t.Errorf("saved token = %q/%q, want rotated access-2/refresh-2", saved.AccessToken, saved.RefreshToken)
+	}
+
+	// A still-valid token is reused without another refresh
+	if _, err := ts.Token(); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if fake.refreshes != 1 {
+		t.Errorf("refreshes = %d, want 1", fake.refreshes)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/drive/v3"
+)
+
+// What to do when a doc with the output name already exists in the output folder
+const (
+	OnExistingVersion   = "version"   // create "<name> (v2)", "<name> (v3)", ...
+	OnExistingOverwrite = "overwrite" // move the existing doc to the trash and create a new one
+	OnExistingAbort     = "abort"     // stop without creating anything
+)
+
+// How the e2e output doc is created
+const (
+	OutputModeBlank = "blank" // create an empty doc and let sync-format rebuild the formatting
+	OutputModeCopy  = "copy"  // copy the input doc so page setup, headers, footers and named styles carry over
+	// OutputModeInterleave copies the input doc and inserts each Chinese line under its English
+	// paragraph, leaving the English formatting untouched so sync-format is not needed
+	OutputModeInterleave = "interleave"
+	// OutputModeTable creates an empty doc holding a two-column table, one line pair per row with
+	// English left and Chinese right, formatted from the source lines
+	OutputModeTable = "table"
+)
+
+const googleDocMimeType = "application/vnd.google-apps.document"
+
+// outputNameData is the data available to the output_name template
+type outputNameData struct {
+	InputTitle string
+	Date       string // run date as YYYY-MM-DD
+	Time       string // run time as HH:MM
+}
+
+// renderOutputName fills the output_name template; a name without template actions is used as is
+func renderOutputName(nameTemplate string, data outputNameData) (string, error) {
+	tmpl, err := template.New("output_name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid output_name template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid output_name template: %v", err)
+	}
+	name := strings.TrimSpace(buf.String())
+	if name == "" {
+		return "", fmt.Errorf("output_name template produced an empty name")
+	}
+	return name, nil
+}
+
+// newOutputNameData builds the template data for an input doc title and run time
+func newOutputNameData(inputTitle string, now time.Time) outputNameData {
+	return outputNameData{
+		InputTitle: strings.TrimSpace(inputTitle),
+		Date:       now.Format("2006-01-02"),
+		Time:       now.Format("15:04"),
+	}
+}
+
+// validateOnExisting checks the on_existing setting, defaulting to version
+func validateOnExisting(value string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(value)); v {
+	case "":
+		return OnExistingVersion, nil
+	case OnExistingVersion, OnExistingOverwrite, OnExistingAbort:
+		return v, nil
+	default:
+		return "", fmt.Errorf("unknown on_existing %q (expected version, overwrite or abort)", value)
+	}
+}
+
+// validateOutputMode checks the output_mode setting, defaulting to blank
+func validateOutputMode(value string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(value)); v {
+	case "":
+		return OutputModeBlank, nil
+	case OutputModeBlank, OutputModeCopy, OutputModeInterleave, OutputModeTable:
+		return v, nil
+	default:
+		return "", fmt.Errorf("unknown output mode %q (expected blank, copy, interleave or table)", value)
+	}
+}
+
+var versionSuffixPattern = regexp.MustCompile(`^(.*) \(v(\d+)\)$`)
+
+// versionedName returns name with the next free " (vN)" suffix given the names already taken
+func versionedName(name string, taken []string) string {
+	highest := 1
+	for _, t := range taken {
+		if m := versionSuffixPattern.FindStringSubmatch(t); m != nil && m[1] == name {
+			if n, err := strconv.Atoi(m[2]); err == nil && n > highest {
+				highest = n
+			}
+		}
+	}
+	return fmt.Sprintf("%s (v%d)", name, highest+1)
+}
+
+// driveQueryString quotes a value for a Drive search query
+func driveQueryString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// listDocsByName lists the non-trashed Google Docs in folderID (the user's root when empty)
+// whose name is name or, when prefix is set, starts with name
+func listDocsByName(ctx context.Context, srv *drive.Service, name, folderID string, prefix bool) ([]*drive.File, error) {
+	if folderID == "" {
+		folderID = "root"
+	}
+	op := "="
+	if prefix {
+		op = "contains"
+	}
+	q := fmt.Sprintf("name %s %s and mimeType = '%s' and trashed = false and %s in parents",
+		op, driveQueryString(name), googleDocMimeType, driveQueryString(folderID))
+
+	var files []*drive.File
+	err := srv.Files.List().Q(q).Fields("nextPageToken", "files(id, name, webViewLink)").
+		SupportsAllDrives(true).IncludeItemsFromAllDrives(true).
+		Pages(ctx, func(page *drive.FileList) error {
+			files = append(files, page.Files...)
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("unable to search output folder: %w", err)
+	}
+	return files, nil
+}
+
+// resolveOutputName applies the on_existing policy to the rendered output name, returning the
+// name to create the doc under
+func resolveOutputName(ctx context.Context, srv *drive.Service, name, folderID, onExisting string) (string, error) {
+	existing, err := listDocsByName(ctx, srv, name, folderID, false)
+	if err != nil {
+		return "", err
+	}
+	if len(existing) == 0 {
+		return name, nil
+	}
+
+	switch onExisting {
+	case OnExistingAbort:
+		return "", fmt.Errorf("a doc named %q already exists in the output folder: %s", name, existing[0].WebViewLink)
+	case OnExistingOverwrite:
+		for _, f := range existing {
+			if _, err := srv.Files.Update(f.Id, &drive.File{Trashed: true}).SupportsAllDrives(true).Context(ctx).Do(); err != nil {
+				return "", fmt.Errorf("unable to move existing doc %s to the trash: %w", f.Id, err)
+			}
+			slog.Info("moved existing output doc to the trash", "doc_id", f.Id, "name", f.Name)
+		}
+		return name, nil
+	default:
+		similar, err := listDocsByName(ctx, srv, name, folderID, true)
+		if err != nil {
+			return "", err
+		}
+		var taken []string
+		for _, f := range similar {
+			taken = append(taken, f.Name)
+		}
+		versioned := versionedName(name, taken)
+		slog.Info("output doc name already taken, adding a version suffix", "name", versioned)
+		return versioned, nil
+	}
+}
+
+// getDocumentInfo fetches only the title and current revision ID of a document
+func getDocumentInfo(ctx context.Context, docsService *docs.Service, docID string) (title, revisionID string, err error) {
+	err = defaultRetryPolicy.do(ctx, "documents.get", func() error {
+		doc, err := docsService.Documents.Get(docID).Fields("title", "revisionId").Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		title, revisionID = doc.Title, doc.RevisionId
+		return nil
+	})
+	return title, revisionID, err
+}
+
+// copyInputDoc copies the input doc under a new name, inside folderID when it is set
+func copyInputDoc(ctx context.Context, srv *drive.Service, inputDocID, name, folderID string) (*drive.File, error) {
+	f := &drive.File{Name: name}
+	if folderID != "" {
+		f.Parents = []string{folderID}
+	}
+	copied, err := srv.Files.Copy(inputDocID, f).Fields("id", "name", "mimeType", "webViewLink").SupportsAllDrives(true).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy input doc: %w", err)
+	}
+	return copied, nil
+}
+
+// resetBodyStyleRequests returns the requests that put every body paragraph back to the doc's
+// Normal text style: no bullets, no alignment or indents of its own, and no run styles such as bold,
+// font, size or colour. The named style definitions themselves are kept.
+func resetBodyStyleRequests(doc *docs.Document) []*docs.Request {
+	if doc.Body == nil || len(doc.Body.Content) == 0 {
+		return nil
+	}
+	endIndex := doc.Body.Content[len(doc.Body.Content)-1].EndIndex
+	if endIndex <= 1 {
+		return nil
+	}
+	bodyRange := &docs.Range{StartIndex: 1, EndIndex: endIndex}
+	reqs := []*docs.Request{
+		{DeleteParagraphBullets: &docs.DeleteParagraphBulletsRequest{Range: bodyRange}},
+		{UpdateParagraphStyle: &docs.UpdateParagraphStyleRequest{
+			Range:          bodyRange,
+			ParagraphStyle: &docs.ParagraphStyle{NamedStyleType: "NORMAL_TEXT"},
+			Fields:         "namedStyleType,alignment,indentStart,indentEnd,indentFirstLine",
+		}},
+	}
+	// The text range stops before the body's final newline
+	if endIndex > 2 {
+		reqs = append(reqs, &docs.Request{UpdateTextStyle: &docs.UpdateTextStyleRequest{
+			Range:     &docs.Range{StartIndex: 1, EndIndex: endIndex - 1},
+			TextStyle: &docs.TextStyle{},
+			Fields:    "bold,italic,underline,fontSize,weightedFontFamily,foregroundColor",
+		}})
+	}
+	return reqs
+}
+
+// plainParagraphRequests returns the requests that put inserted paragraphs in the Normal text style
+// without bullets. Inserted text takes the heading or bullet of the paragraph it went into, and
+// sync-format can't remove either.
+func plainParagraphRequests(startIndex, endIndex int64) []*docs.Request {
+	r := &docs.Range{StartIndex: startIndex, EndIndex: endIndex}
+	return []*docs.Request{
+		{UpdateParagraphStyle: &docs.UpdateParagraphStyleRequest{
+			Range:          r,
+			ParagraphStyle: &docs.ParagraphStyle{NamedStyleType: "NORMAL_TEXT"},
+			Fields:         "namedStyleType",
+		}},
+		{DeleteParagraphBullets: &docs.DeleteParagraphBulletsRequest{Range: r}},
+	}
+}
+
+// resetBodyStyles clears the paragraph and text styles of a copied doc's body, so text written into
+// it doesn't inherit the style of whatever survived the body replacement. sync-format only adds
+// styles and could not remove them later.
+func resetBodyStyles(ctx context.Context, docsService *docs.Service, docID string) error {
+	doc, err := getDocument(ctx, docsService, docID)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve document: %w", err)
+	}
+	reqs := resetBodyStyleRequests(doc)
+	if len(reqs) == 0 {
+		return nil
+	}
+	_, err = batchUpdateDocument(ctx, docsService, docID, &docs.BatchUpdateDocumentRequest{Requests: reqs})
+	if err != nil {
+		return fmt.Errorf("unable to reset body styles: %w", err)
+	}
+	return nil
+}
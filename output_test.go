@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/docs/v1"
+)
+
+func TestRenderOutputName(t *testing.T) {
+	data := newOutputNameData(" Sunday Sermon ", time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC))
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+		wantErr  bool
+	}{
+		{"Literal name", "Hello4", "Hello4", false},
+		{"Title and date", "{{.InputTitle}} - 中英 - {{.Date}}", "Sunday Sermon - 中英 - 2024-03-10", false},
+		{"Time", "{{.Date}} {{.Time}}", "2024-03-10 09:30", false},
+		{"Unknown field", "{{.Speaker}}", "", true},
+		{"Malformed template", "{{.InputTitle", "", true},
+		{"Empty result", "  ", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := renderOutputName(tt.template, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderOutputName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("renderOutputName() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestVersionedName(t *testing.T) {
+	tests := []struct {
+		name     string
+		taken    []string
+		expected string
+	}{
+		{"Only the original exists", []string{"Sermon"}, "Sermon (v2)"},
+		{"Versions exist", []string{"Sermon", "Sermon (v2)", "Sermon (v4)"}, "Sermon (v5)"},
+		{"Other docs sharing the prefix are ignored", []string{"Sermon", "Sermon notes (v9)"}, "Sermon (v2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := versionedName("Sermon", tt.taken)
+			if result != tt.expected {
+				t.Errorf("versionedName() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateOnExisting(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+		wantErr  bool
+	}{
+		{"", OnExistingVersion, false},
+		{"Overwrite", OnExistingOverwrite, false},
+		{"abort", OnExistingAbort, false},
+		{"replace", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := validateOnExisting(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateOnExisting() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("validateOnExisting() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDriveQueryString(t *testing.T) {
+	if got := driveQueryString(`Pastor's \ notes`); got != `'Pastor\'s \\ notes'` {
+		t.Errorf("driveQueryString() = %s", got)
+	}
+}
+
+func TestValidateOutputMode(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+		wantErr  bool
+	}{
+		{"", OutputModeBlank, false},
+		{"copy", OutputModeCopy, false},
+		{" Blank ", OutputModeBlank, false},
+		{"Table", OutputModeTable, false},
+		{"template", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := validateOutputMode(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateOutputMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("validateOutputMode() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResetBodyStyleRequests(t *testing.T) {
+	doc := buildParagraphDocument("Welcome", "欢迎")
+	end := doc.Body.Content[1].EndIndex
+	reqs := resetBodyStyleRequests(doc)
+	if len(reqs) != 3 {
+		t.Fatalf("got %d requests, want 3: %+v", len(reqs), reqs)
+	}
+	if r := reqs[0].DeleteParagraphBullets; r == nil || r.Range.EndIndex != end {
+		t.Errorf("bullets request = %+v", r)
+	}
+	paragraph := reqs[1].UpdateParagraphStyle
+	if paragraph == nil || paragraph.ParagraphStyle.NamedStyleType != "NORMAL_TEXT" || paragraph.ParagraphStyle.Alignment != "" {
+		t.Fatalf("paragraph request = %+v", paragraph)
+	}
+	for _, field := range []string{"namedStyleType", "alignment", "indentStart", "indentEnd", "indentFirstLine"} {
+		if !strings.Contains(paragraph.Fields, field) {
+			t.Errorf("paragraph fields %q do not reset %s", paragraph.Fields, field)
+		}
+	}
+	text := reqs[2].UpdateTextStyle
+	if text == nil || text.Range.StartIndex != 1 || text.Range.EndIndex != end-1 || text.TextStyle.Bold {
+		t.Fatalf("text request = %+v", text)
+	}
+	for _, field := range []string{"bold", "italic", "underline", "fontSize", "weightedFontFamily", "foregroundColor"} {
+		if !strings.Contains(text.Fields, field) {
+			t.Errorf("text fields %q do not reset %s", text.Fields, field)
+		}
+	}
+
+	if reqs := resetBodyStyleRequests(&docs.Document{Body: &docs.Body{}}); reqs != nil {
+		t.Errorf("empty body got requests: %+v", reqs)
+	}
+}
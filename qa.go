@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/drive/v3"
+)
+
+// QACheck identifies a heuristic that flags a suspicious translation
+type QACheck string
+
+const (
+	QAMissing      QACheck = "missing_translation"
+	QAIdentical    QACheck = "identical_to_source"
+	QAUntranslated QACheck = "untranslated_english"
+	QANumbers      QACheck = "number_mismatch"
+	QAScripture    QACheck = "scripture_mismatch"
+	QAGlossary     QACheck = "glossary_term"
+	QALength       QACheck = "length_ratio"
+)
+
+// qaWeights is how strongly each check suggests a bad translation, from 0 to 1
+var qaWeights = map[QACheck]float64{
+	QAMissing:      1,
+	QAIdentical:    1,
+	QAUntranslated: 0.6,
+	QANumbers:      0.8,
+	QAScripture:    0.8,
+	QAGlossary:     0.5,
+	QALength:       0.4,
+}
+
+// Length ratio outliers: Chinese characters per English letter, compared with the doc's median
+const (
+	qaDefaultLengthRatio = 0.35 // used when the doc has too few long lines for a median
+	qaMinRatioLines      = 5
+	qaMinEnglishLetters  = 20 // shorter lines vary too much to judge
+	qaRatioTolerance     = 2.5
+)
+
+// qaHighlightColor is the background given to flagged lines
+var qaHighlightColor = &docs.RgbColor{Red: 1, Green: 0.85, Blue: 0.4}
+
+// qaFlag is one problem found on a line pair
+type qaFlag struct {
+	Check   QACheck
+	Message string
+}
+
+// qaResult is a line pair of a bilingual doc with its flags and combined score
+type qaResult struct {
+	Pair     translationPair
+	Line     int // 1-based pair number
+	Elements []*docs.StructuralElement
+	Flags    []qaFlag
+	Score    float64
+}
+
+var (
+	// untranslatedPattern matches three or more English words in a row
+	untranslatedPattern = regexp.MustCompile(`[A-Za-z][A-Za-z'’-]*(?:[\s,;:]+[A-Za-z][A-Za-z'’-]*){2,}`)
+	numberPattern       = regexp.MustCompile(`\d+(?:,\d{3})*(?:\.\d+)?`)
+	fullWidthDigits     = strings.NewReplacer("０", "0", "１", "1", "２", "2", "３", "3", "４", "4", "５", "5", "６", "6", "７", "7", "８", "8", "９", "9", "：", ":", "，", ",", "．", ".")
+)
+
+// englishLetters counts the letters of an English line
+func englishLetters(text string) int {
+	n := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// chineseCharacters counts the Chinese characters of a line
+func chineseCharacters(text string) int {
+	n := 0
+	for _, r := range text {
+		if containsChinese(string(r)) {
+			n++
+		}
+	}
+	return n
+}
+
+// lengthRatio returns the Chinese characters per English letter of a pair, and whether the
+// English is long enough for the ratio to mean anything
+func lengthRatio(pair translationPair) (float64, bool) {
+	letters := englishLetters(pair.English)
+	if letters < qaMinEnglishLetters || pair.Chinese == "" {
+		return 0, false
+	}
+	return float64(chineseCharacters(pair.Chinese)) / float64(letters), true
+}
+
+// medianLengthRatio returns the median length ratio of the doc's long lines
+func medianLengthRatio(pairs []translationPair) float64 {
+	var ratios []float64
+	for _, pair := range pairs {
+		if ratio, ok := lengthRatio(pair); ok {
+			ratios = append(ratios, ratio)
+		}
+	}
+	if len(ratios) < qaMinRatioLines {
+		return qaDefaultLengthRatio
+	}
+	sort.Float64s(ratios)
+	mid := len(ratios) / 2
+	if len(ratios)%2 == 0 {
+		return (ratios[mid-1] + ratios[mid]) / 2
+	}
+	return ratios[mid]
+}
+
+// numbersIn returns the numbers of a line that are 10 or more; smaller ones are often spelled out
+// in Chinese. Digit groups are joined, so "1,000" is 1000.
+func numbersIn(text string) map[string]bool {
+	numbers := make(map[string]bool)
+	for _, m := range numberPattern.FindAllString(fullWidthDigits.Replace(text), -1) {
+		m = strings.ReplaceAll(m, ",", "")
+		if value, err := strconv.ParseFloat(m, 64); err == nil && value >= 10 {
+			numbers[m] = true
+		}
+	}
+	return numbers
+}
+
+// checkNumbers flags numbers that appear on only one side of a pair. Numbers inside scripture
+// references are left to checkScripture.
+func checkNumbers(pair translationPair) []qaFlag {
+	english := pair.English
+	refs := findScriptureRefs(english)
+	for i := len(refs) - 1; i >= 0; i-- {
+		english = english[:refs[i].Start] + " " + english[refs[i].End:]
+	}
+	englishNumbers := numbersIn(english)
+	allEnglish := numbersIn(pair.English)
+	chineseNumbers := numbersIn(pair.Chinese)
+
+	var missing, extra []string
+	for n := range englishNumbers {
+		if !chineseNumbers[n] {
+			missing = append(missing, n)
+		}
+	}
+	for n := range chineseNumbers {
+		if !allEnglish[n] {
+			extra = append(extra, n)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+
+	var flags []qaFlag
+	if len(missing) > 0 {
+		flags = append(flags, qaFlag{QANumbers, fmt.Sprintf("%s missing from the Chinese", strings.Join(missing, ", "))})
+	}
+	if len(extra) > 0 {
+		flags = append(flags, qaFlag{QANumbers, fmt.Sprintf("%s not in the English", strings.Join(extra, ", "))})
+	}
+	return flags
+}
+
+// checkScripture flags scripture references whose chapter and verse don't appear in the Chinese
+func checkScripture(pair translationPair) []qaFlag {
+	chinese := strings.ReplaceAll(fullWidthDigits.Replace(pair.Chinese), " ", "")
+	var flags []qaFlag
+	for _, ref := range findScriptureRefs(pair.English) {
+		if !strings.Contains(chinese, ref.verseRange()) {
+			flags = append(flags, qaFlag{QAScripture, fmt.Sprintf("%s should read %s", ref, ref.Chinese())})
+		}
+	}
+	return flags
+}
+
+// checkUntranslated flags runs of English words left in a Chinese line. Glossary terms and
+// scripture references are expected to stay in English now and then, so they don't count.
+func checkUntranslated(pair translationPair, terms []glossaryEntry) []qaFlag {
+	chinese := pair.Chinese
+	refs := findScriptureRefs(chinese)
+	for i := len(refs) - 1; i >= 0; i-- {
+		chinese = chinese[:refs[i].Start] + " " + chinese[refs[i].End:]
+	}
+	for _, term := range terms {
+		chinese = glossaryTermPattern(term.English).ReplaceAllString(chinese, " ")
+	}
+	var flags []qaFlag
+	for _, m := range untranslatedPattern.FindAllString(chinese, -1) {
+		flags = append(flags, qaFlag{QAUntranslated, fmt.Sprintf("%q left in English", truncateText(strings.TrimSpace(m), 40))})
+	}
+	return flags
+}
+
+// checkLength flags a pair whose Chinese is much shorter or longer than its English, compared
+// with the other lines of the doc
+func checkLength(pair translationPair, median float64) []qaFlag {
+	ratio, ok := lengthRatio(pair)
+	if !ok || median <= 0 {
+		return nil
+	}
+	switch {
+	case ratio < median/qaRatioTolerance:
+		return []qaFlag{{QALength, fmt.Sprintf("Chinese is unusually short (%.2f characters per letter, typical %.2f)", ratio, median)}}
+	case ratio > median*qaRatioTolerance:
+		return []qaFlag{{QALength, fmt.Sprintf("Chinese is unusually long (%.2f characters per letter, typical %.2f)", ratio, median)}}
+	}
+	return nil
+}
+
+// qaScore combines the weights of a pair's flags as independent signals into a score from 0 to 1
+func qaScore(flags []qaFlag) float64 {
+	clean := 1.0
+	for _, flag := range flags {
+		clean *= 1 - qaWeights[flag.Check]
+	}
+	return math.Round((1-clean)*100) / 100
+}
+
+// scoreTranslations runs every check over the pairs of a bilingual doc and returns the flagged
+// pairs in doc order. An English line repeated right after itself with no Chinese in between is
+// the source copied in place of its translation, and is reported once as such.
+func scoreTranslations(pairs []bilingualPair, terms []glossaryEntry) []qaResult {
+	lines := make([]translationPair, len(pairs))
+	for i, pair := range pairs {
+		lines[i].English = pair.Line.Text
+		var chinese []string
+		for _, element := range pair.Elements[1:] {
+			chinese = append(chinese, paragraphText(element))
+		}
+		lines[i].Chinese = strings.Join(chinese, "\n")
+	}
+	median := medianLengthRatio(lines)
+	violations := make(map[int][]glossaryViolation)
+	for _, v := range checkGlossary(lines, terms) {
+		violations[v.Pair-1] = append(violations[v.Pair-1], v)
+	}
+
+	var results []qaResult
+	for i := 0; i < len(pairs); i++ {
+		pair := lines[i]
+		result := qaResult{Pair: pair, Line: i + 1, Elements: pairs[i].Elements[1:]}
+		if pair.Chinese == "" {
+			result.Elements = pairs[i].Elements[:1]
+			if i+1 < len(pairs) && lines[i+1].Chinese == "" && lines[i+1].English == pair.English {
+				result.Elements = append(result.Elements, pairs[i+1].Elements[0])
+				result.Flags = []qaFlag{{QAIdentical, "the English line is repeated instead of translated"}}
+				i++
+			} else {
+				result.Flags = []qaFlag{{QAMissing, "no Chinese line follows"}}
+			}
+		} else {
+			result.Flags = append(result.Flags, checkUntranslated(pair, terms)...)
+			result.Flags = append(result.Flags, checkNumbers(pair)...)
+			result.Flags = append(result.Flags, checkScripture(pair)...)
+			for _, v := range violations[i] {
+				result.Flags = append(result.Flags, qaFlag{QAGlossary, fmt.Sprintf("%q should be translated as %q", v.Term.English, v.Term.Chinese)})
+			}
+			result.Flags = append(result.Flags, checkLength(pair, median)...)
+		}
+		if len(result.Flags) == 0 {
+			continue
+		}
+		result.Score = qaScore(result.Flags)
+		results = append(results, result)
+	}
+	return results
+}
+
+// isQAHighlight reports whether a text run carries the qa highlight color
+func isQAHighlight(style *docs.TextStyle) bool {
+	if style == nil || style.BackgroundColor == nil || style.BackgroundColor.Color == nil || style.BackgroundColor.Color.RgbColor == nil {
+		return false
+	}
+	c := style.BackgroundColor.Color.RgbColor
+	near := func(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+	return near(c.Red, qaHighlightColor.Red) && near(c.Green, qaHighlightColor.Green) && near(c.Blue, qaHighlightColor.Blue)
+}
+
+// qaHighlightRequests highlights the flagged lines and clears the highlight a previous qa run left
+// on lines that are no longer flagged
+func qaHighlightRequests(doc *docs.Document, results []qaResult) []*docs.Request {
+	flagged := make(map[int64]bool)
+	var requests []*docs.Request
+	for _, result := range results {
+		for _, element := range result.Elements {
+			flagged[element.StartIndex] = true
+			requests = append(requests, &docs.Request{UpdateTextStyle: &docs.UpdateTextStyleRequest{
+				Range: &docs.Range{StartIndex: element.StartIndex, EndIndex: element.EndIndex - 1},
+				TextStyle: &docs.TextStyle{BackgroundColor: &docs.OptionalColor{
+					Color: &docs.Color{RgbColor: qaHighlightColor},
+				}},
+				Fields: "backgroundColor",
+			}})
+		}
+	}
+	if doc.Body == nil {
+		return requests
+	}
+	for _, element := range doc.Body.Content {
+		if element.Paragraph == nil || flagged[element.StartIndex] {
+			continue
+		}
+		for _, pe := range element.Paragraph.Elements {
+			if pe.TextRun != nil && isQAHighlight(pe.TextRun.TextStyle) {
+				requests = append(requests, &docs.Request{UpdateTextStyle: &docs.UpdateTextStyleRequest{
+					Range:     &docs.Range{StartIndex: pe.StartIndex, EndIndex: pe.EndIndex},
+					TextStyle: &docs.TextStyle{},
+					Fields:    "backgroundColor",
+				}})
+			}
+		}
+	}
+	return requests
+}
+
+// qaCommentText describes the flags of a pair for a Drive comment
+func qaCommentText(result qaResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (score %.2f):", qaCommentPrefix, result.Score)
+	for _, flag := range result.Flags {
+		fmt.Fprintf(&sb, "\n- %s", flag.Message)
+	}
+	return sb.String()
+}
+
+// qaCommentPrefix starts every comment qa adds, so a later run can find them
+const qaCommentPrefix = "Translation QA"
+
+// qaQuote returns the text a comment on a flagged pair quotes: its Chinese, or its English when
+// there is no Chinese
+func qaQuote(result qaResult) string {
+	if result.Pair.Chinese != "" {
+		return result.Pair.Chinese
+	}
+	return result.Pair.English
+}
+
+// isQAComment reports whether a comment was added by an earlier qa run and is still open
+func isQAComment(comment *drive.Comment) bool {
+	return !comment.Resolved && !comment.Deleted && comment.QuotedFileContent != nil && strings.HasPrefix(comment.Content, qaCommentPrefix)
+}
+
+// planQAComments compares the open qa comments of a doc with the flagged pairs, the way highlight
+// mode does with its highlights: flagged pairs that already have a comment quoting them get no
+// second one, and comments on lines that are no longer flagged are to be resolved
+func planQAComments(existing []*drive.Comment, results []qaResult) (create []qaResult, resolve []*drive.Comment) {
+	flagged := make(map[string]bool)
+	for _, result := range results {
+		flagged[qaQuote(result)] = true
+	}
+	commented := make(map[string]bool)
+	for _, comment := range existing {
+		if !isQAComment(comment) {
+			continue
+		}
+		quoted := comment.QuotedFileContent.Value
+		if flagged[quoted] && !commented[quoted] {
+			commented[quoted] = true
+			continue
+		}
+		resolve = append(resolve, comment)
+	}
+	for _, result := range results {
+		if quoted := qaQuote(result); !commented[quoted] {
+			commented[quoted] = true
+			create = append(create, result)
+		}
+	}
+	return create, resolve
+}
+
+// listComments returns all comments of a doc
+func listComments(ctx context.Context, srv *drive.Service, docID string) ([]*drive.Comment, error) {
+	var comments []*drive.Comment
+	err := defaultRetryPolicy.do(ctx, "comments.list", func() error {
+		comments = nil
+		return srv.Comments.List(docID).Fields("nextPageToken", "comments(id,content,resolved,deleted,quotedFileContent)").
+			PageSize(100).Pages(ctx, func(page *drive.CommentList) error {
+			comments = append(comments, page.Comments...)
+			return nil
+		})
+	})
+	return comments, err
+}
+
+// syncQAComments adds a comment quoting each flagged line that doesn't have one yet and resolves
+// the comments of earlier runs on lines that are no longer flagged. Drive can't anchor comments to
+// text in a Google Doc, so they are listed in the comments pane with the quoted line. It returns
+// the number of comments added and resolved.
+func syncQAComments(ctx context.Context, srv *drive.Service, docID string, results []qaResult) (int, int, error) {
+	existing, err := listComments(ctx, srv, docID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to list comments: %v", err)
+	}
+	create, resolve := planQAComments(existing, results)
+	for _, comment := range resolve {
+		reply := &drive.Reply{Action: "resolve", Content: qaCommentPrefix + ": no longer flagged"}
+		err := defaultRetryPolicy.doWrite(ctx, "replies.create", func() error {
+			_, err := srv.Replies.Create(docID, comment.Id, reply).Fields("id").Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			return 0, 0, fmt.Errorf("unable to resolve comment %s: %v", comment.Id, err)
+		}
+	}
+	for _, result := range create {
+		comment := &drive.Comment{
+			Content:           qaCommentText(result),
+			QuotedFileContent: &drive.CommentQuotedFileContent{MimeType: "text/plain", Value: qaQuote(result)},
+		}
+		err := defaultRetryPolicy.doWrite(ctx, "comments.create", func() error {
+			_, err := srv.Comments.Create(docID, comment).Fields("id").Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			return 0, 0, fmt.Errorf("unable to add comment on line %d: %v", result.Line, err)
+		}
+	}
+	return len(create), len(resolve), nil
+}
+
+// printQAReport prints the flagged pairs, highest score first
+func printQAReport(total int, results []qaResult) {
+	sorted := append([]qaResult(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Score > sorted[j].Score })
+
+	fmt.Println("\n=== Translation QA ===")
+	fmt.Printf("Line pairs checked: %d\n", total)
+	fmt.Printf("Flagged: %d\n", len(sorted))
+	for _, result := range sorted {
+		fmt.Printf("\n  line %d (score %.2f): %s\n", result.Line, result.Score, truncateText(result.Pair.English, 80))
+		if result.Pair.Chinese != "" {
+			fmt.Printf("    %s\n", truncateText(result.Pair.Chinese, 80))
+		}
+		for _, flag := range result.Flags {
+			fmt.Printf("    - %s: %s\n", flag.Check, flag.Message)
+		}
+	}
+	fmt.Println("======================")
+}
+
+// checkTranslationQuality scores the line pairs of a bilingual doc and marks the suspicious ones
+// with a highlight, or with comments when comments is set
+func checkTranslationQuality(c *clients, cfg *appConfig, docURL string, comments, dryRun bool) {
+	ctx := context.Background()
+	docID := extractDocumentID(docURL)
+	if docID == "" {
+		logFatal("could not extract document ID from URL", "url", docURL)
+	}
+	terms, err := loadGlossary(firstNonEmpty(cfg.GlossaryFile, defaultGlossaryFile))
+	if err != nil {
+		logFatal("unable to load glossary", "err", err)
+	}
+	doc, err := getDocument(ctx, c.Docs, docID)
+	if err != nil {
+		logFatal("unable to retrieve document", "err", err)
+	}
+
+	pairs := collectBilingualPairs(doc)
+	results := scoreTranslations(pairs, terms)
+	slog.Info("scored translations", "pairs", len(pairs), "flagged", len(results), "glossary_terms", len(terms))
+	printQAReport(len(pairs), results)
+	if dryRun {
+		return
+	}
+
+	if comments {
+		srv, err := c.UserDrive(ctx)
+		if err != nil {
+			logFatal("unable to set up Drive access", "err", err)
+		}
+		added, resolved, err := syncQAComments(ctx, srv, docID, results)
+		if err != nil {
+			logFatal("unable to update comments", "err", err)
+		}
+		fmt.Printf("Added %d comments, resolved %d comments on lines no longer flagged\n", added, resolved)
+		return
+	}
+	requests := qaHighlightRequests(doc, results)
+	if len(requests) == 0 {
+		return
+	}
+	if _, err := batchUpdateDocument(ctx, c.Docs, docID, &docs.BatchUpdateDocumentRequest{Requests: requests}); err != nil {
+		logFatal("unable to update document", "err", err)
+	}
+	fmt.Printf("Highlighted %d flagged line pairs\n", len(results))
+}
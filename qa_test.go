@@ -0,0 +1,205 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/drive/v3"
+)
+
+// qaChecks returns the checks of a list of flags
+func qaChecks(flags []qaFlag) []QACheck {
+	var checks []QACheck
+	for _, flag := range flags {
+		checks = append(checks, flag.Check)
+	}
+	return checks
+}
+
+func TestQAPairChecks(t *testing.T) {
+	terms := []glossaryEntry{{English: "Holy Spirit", Chinese: "圣灵"}}
+	tests := []struct {
+		name string
+		pair translationPair
+		want []QACheck
+	}{
+		{"Clean pair", translationPair{"We gave 120 meals in 2024", "我们在2024年送出了120份餐"}, nil},
+		{"Small numbers may be spelled out", translationPair{"Read 3 chapters", "读三章"}, nil},
+		{"Full-width digits count", translationPair{"Room 201", "２０１室"}, nil},
+		{"Number missing", translationPair{"We gave 120 meals", "我们送出了餐"}, []QACheck{QANumbers}},
+		{"Number added", translationPair{"We gave meals", "我们送出了150份餐"}, []QACheck{QANumbers}},
+		{"Reference kept", translationPair{"Read John 3:16 today", "今天读约翰福音3:16"}, nil},
+		{"Full-width reference kept", translationPair{"Read Rom. 8:28-30", "读罗马书８：２８-３０"}, nil},
+		{"Reference changed", translationPair{"Read John 3:16 today", "今天读约翰福音3:17"}, []QACheck{QANumbers, QAScripture}},
+		{"English left in", translationPair{"Let us pray together now", "让我们 pray together now"}, []QACheck{QAUntranslated}},
+		{"Glossary term left in English", translationPair{"Filled with the Holy Spirit", "被 Holy Spirit 充满"}, []QACheck{QAGlossary}},
+		{"Names are not untranslated runs", translationPair{"John 3:16", "John 3:16 约翰福音"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var flags []qaFlag
+			flags = append(flags, checkUntranslated(tt.pair, terms)...)
+			flags = append(flags, checkNumbers(tt.pair)...)
+			flags = append(flags, checkScripture(tt.pair)...)
+			for _, v := range checkGlossary([]translationPair{tt.pair}, terms) {
+				flags = append(flags, qaFlag{QAGlossary, v.Term.English})
+			}
+			if got := qaChecks(flags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checks = %v, want %v (%+v)", got, tt.want, flags)
+			}
+		})
+	}
+}
+
+func TestCheckLength(t *testing.T) {
+	english := "The Lord is my shepherd, I shall not want" // 32 letters
+	tests := []struct {
+		name    string
+		chinese string
+		want    []QACheck
+	}{
+		{"Typical", "耶和华是我的牧者，我必不致缺乏", nil},
+		{"Too short", "牧者", []QACheck{QALength}},
+		{"Too long", "耶和华是我的牧者我必不致缺乏耶和华是我的牧者我必不致缺乏耶和华是我的牧者", []QACheck{QALength}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := checkLength(translationPair{English: english, Chinese: tt.chinese}, qaDefaultLengthRatio)
+			if got := qaChecks(flags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checks = %v, want %v (%+v)", got, tt.want, flags)
+			}
+		})
+	}
+	if flags := checkLength(translationPair{English: "Amen", Chinese: "阿们阿们阿们阿们"}, qaDefaultLengthRatio); flags != nil {
+		t.Errorf("short English line was judged: %+v", flags)
+	}
+}
+
+func TestMedianLengthRatio(t *testing.T) {
+	pair := func(chinese string) translationPair {
+		return translationPair{English: "abcde fghij klmno pqrst", Chinese: chinese} // 20 letters
+	}
+	few := []translationPair{pair("一二三四")}
+	if got := medianLengthRatio(few); got != qaDefaultLengthRatio {
+		t.Errorf("median of one line = %v, want the default %v", got, qaDefaultLengthRatio)
+	}
+	pairs := []translationPair{pair("一二"), pair("一二三四"), pair("一二三四五六"), pair("一二三四五六七八"), pair("一二三四五六七八九十"), {English: "Hi", Chinese: "你好"}}
+	if got := medianLengthRatio(pairs); got != 0.3 {
+		t.Errorf("medianLengthRatio() = %v, want 0.3", got)
+	}
+}
+
+func TestQAScore(t *testing.T) {
+	tests := []struct {
+		flags []qaFlag
+		want  float64
+	}{
+		{nil, 0},
+		{[]qaFlag{{Check: QALength}}, 0.4},
+		{[]qaFlag{{Check: QAGlossary}, {Check: QALength}}, 0.7},
+		{[]qaFlag{{Check: QAMissing}, {Check: QALength}}, 1},
+	}
+	for _, tt := range tests {
+		if got := qaScore(tt.flags); got != tt.want {
+			t.Errorf("qaScore(%v) = %v, want %v", qaChecks(tt.flags), got, tt.want)
+		}
+	}
+}
+
+func TestScoreTranslations(t *testing.T) {
+	doc := buildParagraphDocument(
+		"Welcome", "欢迎",
+		"",
+		"No translation here",
+		"Amen", "Amen",
+		"",
+		"We gave 120 meals", "我们送出了餐",
+		"Go in peace", "平安地去吧",
+	)
+	results := scoreTranslations(collectBilingualPairs(doc), nil)
+
+	want := []struct {
+		line   int
+		checks []QACheck
+		starts []int64
+	}{
+		{2, []QACheck{QAMissing}, []int64{doc.Body.Content[3].StartIndex}},
+		{3, []QACheck{QAIdentical}, []int64{doc.Body.Content[4].StartIndex, doc.Body.Content[5].StartIndex}},
+		{5, []QACheck{QANumbers}, []int64{doc.Body.Content[8].StartIndex}},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d flagged pairs, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		r := results[i]
+		var starts []int64
+		for _, element := range r.Elements {
+			starts = append(starts, element.StartIndex)
+		}
+		if r.Line != w.line || !reflect.DeepEqual(qaChecks(r.Flags), w.checks) || !reflect.DeepEqual(starts, w.starts) {
+			t.Errorf("result %d = line %d %v at %v, want line %d %v at %v", i, r.Line, qaChecks(r.Flags), starts, w.line, w.checks, w.starts)
+		}
+		if r.Score != qaScore(r.Flags) {
+			t.Errorf("result %d score = %v", i, r.Score)
+		}
+	}
+}
+
+func TestQAHighlightRequests(t *testing.T) {
+	doc := buildParagraphDocument("We gave 120 meals", "我们送出了餐", "Go in peace", "平安地去吧")
+	// A previous run highlighted the last line, which has since been fixed
+	last := doc.Body.Content[3].Paragraph.Elements[0]
+	last.TextRun.TextStyle = &docs.TextStyle{BackgroundColor: &docs.OptionalColor{Color: &docs.Color{RgbColor: &docs.RgbColor{Red: 1, Green: 0.85, Blue: 0.4}}}}
+
+	results := scoreTranslations(collectBilingualPairs(doc), nil)
+	requests := qaHighlightRequests(doc, results)
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2: %+v", len(requests), requests)
+	}
+
+	flagged := doc.Body.Content[1]
+	set := requests[0].UpdateTextStyle
+	if set.Range.StartIndex != flagged.StartIndex || set.Range.EndIndex != flagged.EndIndex-1 || !isQAHighlight(set.TextStyle) {
+		t.Errorf("highlight request = %+v", set)
+	}
+	clear := requests[1].UpdateTextStyle
+	if clear.Range.StartIndex != last.StartIndex || clear.TextStyle.BackgroundColor != nil || clear.Fields != "backgroundColor" {
+		t.Errorf("clear request = %+v", clear)
+	}
+}
+
+func TestPlanQAComments(t *testing.T) {
+	comment := func(id, content, quoted string, resolved bool) *drive.Comment {
+		return &drive.Comment{Id: id, Content: content, Resolved: resolved, QuotedFileContent: &drive.CommentQuotedFileContent{Value: quoted}}
+	}
+	existing := []*drive.Comment{
+		comment("kept", "Translation QA (score 0.80):\n- 120 missing from the Chinese", "我们送出了餐", false),
+		comment("fixed", "Translation QA (score 1.00):\n- no Chinese line follows", "Go in peace", false),
+		comment("mine", "Check this wording", "平安地去吧", false),
+		comment("old", "Translation QA (score 0.40):", "欢迎", true),
+	}
+	results := []qaResult{
+		{Pair: translationPair{English: "We gave 120 meals", Chinese: "我们送出了餐"}, Line: 1},
+		{Pair: translationPair{English: "Welcome", Chinese: "欢迎"}, Line: 2},
+		{Pair: translationPair{English: "Amen"}, Line: 3},
+	}
+
+	create, resolve := planQAComments(existing, results)
+	var created []int
+	for _, result := range create {
+		created = append(created, result.Line)
+	}
+	if want := []int{2, 3}; !reflect.DeepEqual(created, want) {
+		t.Errorf("comments created for lines %v, want %v", created, want)
+	}
+	if len(resolve) != 1 || resolve[0].Id != "fixed" {
+		t.Errorf("resolved = %+v, want only the comment on the fixed line", resolve)
+	}
+
+	// A second run over the same flags adds nothing
+	existing = append(existing, comment("new2", "Translation QA (score 0.40):", "欢迎", false), comment("new3", "Translation QA (score 1.00):", "Amen", false))
+	if create, _ := planQAComments(existing, results); len(create) != 0 {
+		t.Errorf("second run creates %d comments, want 0", len(create))
+	}
+}
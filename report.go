@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+)
+
+// SyncReportRow is one English line pair in a side-by-side report
+type SyncReportRow struct {
+	LoopID         int
+	SourceLine     int
+	SourceText     string
+	TargetLine     int
+	TargetText     string
+	ChineseLine    int
+	ChineseText    string
+	Decision       *MatchDecision
+	Applied        *LineFeatures
+	ChineseApplied *LineFeatures
+	Mismatch       bool
+	Warning        string
+	Reused         string // where a translation reused from the translation memory came from
+}
+
+// SyncReport collects per-line decisions of a sync-format or compare run for HTML output
+type SyncReport struct {
+	Title       string
+	SourceDocID string
+	TargetDocID string
+	GeneratedAt time.Time
+	Rows        []*SyncReportRow
+	Error       string
+}
+
+// newSyncReport creates an empty report for a source/target document pair
+func newSyncReport(title, sourceDocID, targetDocID string) *SyncReport {
+	return &SyncReport{
+		Title:       title,
+		SourceDocID: sourceDocID,
+		TargetDocID: targetDocID,
+		GeneratedAt: time.Now(),
+	}
+}
+
+// addEnglishLine records an English target line and the source line it was matched against.
+// All report methods are no-ops on a nil report so callers don't need to check whether reporting is enabled.
+func (r *SyncReport) addEnglishLine(loopID, sourceLine int, sourceText string, targetLine int, targetText string, decision *MatchDecision, applied *LineFeatures, applyErr error) {
+	if r == nil {
+		return
+	}
+	row := &SyncReportRow{
+		LoopID:     loopID,
+		SourceLine: sourceLine,
+		SourceText: sourceText,
+		TargetLine: targetLine,
+		TargetText: targetText,
+		Decision:   decision,
+		Applied:    applied,
+		Mismatch:   decision != nil && !decision.LinesMatch,
+	}
+	if applyErr != nil {
+		row.Warning = fmt.Sprintf("Failed to apply formatting: %v", applyErr)
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// addChineseLine attaches a Chinese target line to the current English row, or starts a new row
+// when the previous row already has a translation
+func (r *SyncReport) addChineseLine(loopID, targetLine int, targetText string, decision *MatchDecision, applied *LineFeatures, applyErr error) {
+	if r == nil {
+		return
+	}
+	var row *SyncReportRow
+	if len(r.Rows) > 0 && r.Rows[len(r.Rows)-1].ChineseText == "" && r.Rows[len(r.Rows)-1].TargetText != "" {
+		row = r.Rows[len(r.Rows)-1]
+	} else {
+		row = &SyncReportRow{LoopID: loopID, Decision: decision, Mismatch: true}
+		r.Rows = append(r.Rows, row)
+	}
+	row.ChineseLine = targetLine
+	row.ChineseText = targetText
+	row.ChineseApplied = applied
+	if applyErr != nil {
+		row.Warning = fmt.Sprintf("Failed to apply formatting: %v", applyErr)
+	}
+}
+
+// setError records the error that stopped the run
+func (r *SyncReport) setError(err error) {
+	if r == nil || err == nil {
+		return
+	}
+	r.Error = err.Error()
+}
+
+// MismatchCount returns the number of highlighted rows
+func (r *SyncReport) MismatchCount() int {
+	count := 0
+	for _, row := range r.Rows {
+		if row.Mismatch {
+			count++
+		}
+	}
+	return count
+}
+
+// buildCompareReportRows lays out aligned source and target lines as side-by-side report rows
+func buildCompareReportRows(report *SyncReport, sourceLines, targetLines []DocumentLine) {
+	var targetEnglish []DocumentLine
+	for _, line := range targetLines {
+		if !isTranslationLine(line.Type) {
+			targetEnglish = append(targetEnglish, line)
+		}
+	}
+	_, targetMatch := alignLineKeys(sourceLines, targetEnglish)
+
+	nextSource := 0
+	englishIndex := 0
+	for _, line := range targetLines {
+		if isTranslationLine(line.Type) {
+			decision := AnalyzeLineMatch("", line.Text, nil, false)
+			report.addChineseLine(0, line.Number, line.Text, decision, nil, nil)
+			continue
+		}
+
+		partner := targetMatch[englishIndex]
+		englishIndex++
+		if partner < 0 {
+			decision := AnalyzeLineMatch("", line.Text, nil, false)
+			report.addEnglishLine(0, 0, "", line.Number, line.Text, decision, nil, nil)
+			report.Rows[len(report.Rows)-1].Mismatch = true
+			continue
+		}
+
+		// Source lines skipped by the alignment are missing from the target
+		for ; nextSource < partner; nextSource++ {
+			report.Rows = append(report.Rows, &SyncReportRow{SourceLine: sourceLines[nextSource].Number, SourceText: sourceLines[nextSource].Text, Mismatch: true})
+		}
+		source := sourceLines[partner]
+		decision := AnalyzeLineMatch(source.Text, line.Text, nil, false)
+		report.addEnglishLine(0, source.Number, source.Text, line.Number, line.Text, decision, nil, nil)
+		nextSource = partner + 1
+	}
+	for ; nextSource < len(sourceLines); nextSource++ {
+		report.Rows = append(report.Rows, &SyncReportRow{SourceLine: sourceLines[nextSource].Number, SourceText: sourceLines[nextSource].Text, Mismatch: true})
+	}
+}
+
+// summarizeLineFeatures renders the applied formatting as a single short line
+func summarizeLineFeatures(features *LineFeatures) string {
+	if features == nil {
+		return ""
+	}
+	var parts []string
+	if features.Alignment != "" {
+		parts = append(parts, features.Alignment)
+	}
+	if features.FontFamily != "" {
+		parts = append(parts, features.FontFamily)
+	}
+	if features.FontSize != nil {
+		parts = append(parts, fmt.Sprintf("%.1fpt", *features.FontSize))
+	}
+	if features.Bold {
+		parts = append(parts, "bold")
+	}
+	if features.Italic {
+		parts = append(parts, "italic")
+	}
+	if features.Underline {
+		parts = append(parts, "underline")
+	}
+	if features.FirstLineIndent != nil && *features.FirstLineIndent != 0 {
+		parts = append(parts, fmt.Sprintf("first-line %.1fpt", *features.FirstLineIndent))
+	}
+	if features.LeftIndent != nil && *features.LeftIndent != 0 {
+		parts = append(parts, fmt.Sprintf("left %.1fpt", *features.LeftIndent))
+	}
+	if features.RightIndent != nil && *features.RightIndent != 0 {
+		parts = append(parts, fmt.Sprintf("right %.1fpt", *features.RightIndent))
+	}
+	if features.TextColor != nil {
+		parts = append(parts, fmt.Sprintf("RGB(%.0f, %.0f, %.0f)", features.TextColor.Red, features.TextColor.Green, features.TextColor.Blue))
+	}
+	if features.HasBullet {
+		parts = append(parts, "bullet")
+	}
+	if features.LeadingTabs > 0 {
+		parts = append(parts, fmt.Sprintf("%d tab(s)", features.LeadingTabs))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// summarizeDecision renders a match decision as a single short line
+func summarizeDecision(decision *MatchDecision) string {
+	if decision == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s, match: %t, follow prev: %t, advance source: %t",
+		decision.LineType, decision.LinesMatch, decision.ShouldFollowPrevStyle, decision.ShouldAdvanceSource)
+}
+
+var syncReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"features": summarizeLineFeatures,
+	"decision": summarizeDecision,
+	"lineNum": func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d", n)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; margin: 24px; color: #222; }
+h1 { font-size: 20px; }
+.meta { color: #666; font-size: 13px; margin-bottom: 16px; }
+.error { background: #fdecea; border: 1px solid #f5c2c0; padding: 8px 12px; margin-bottom: 16px; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; vertical-align: top; text-align: left; }
+th { background: #f4f4f4; position: sticky; top: 0; }
+td.num { color: #888; white-space: nowrap; }
+td.small { color: #555; font-size: 12px; }
+tr.mismatch td { background: #fdecea; }
+tr.warning td { background: #fff4e5; }
+tr.reused td { background: #eef5fc; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">
+Source: {{.SourceDocID}} &middot; Target: {{.TargetDocID}} &middot; Generated: {{.GeneratedAt.Format "2006-01-02 15:04:05"}} &middot; Rows: {{len .Rows}} &middot; Mismatches: {{.MismatchCount}}
+</div>
+{{if .Error}}<div class="error">Stopped with error: {{.Error}}</div>{{end}}
+<table>
+<tr><th>Loop</th><th>#</th><th>Source</th><th>#</th><th>Target English</th><th>#</th><th>Target Chinese</th><th>Decision</th><th>Formatting applied</th></tr>
+{{range .Rows}}<tr class="{{if .Mismatch}}mismatch{{else if .Warning}}warning{{else if .Reused}}reused{{end}}">
+<td class="num">{{lineNum .LoopID}}</td>
+<td class="num">{{lineNum .SourceLine}}</td><td>{{.SourceText}}</td>
+<td class="num">{{lineNum .TargetLine}}</td><td>{{.TargetText}}</td>
+<td class="num">{{lineNum .ChineseLine}}</td><td>{{.ChineseText}}</td>
+<td class="small">{{decision .Decision}}</td>
+<td class="small">{{features .Applied}}{{if .ChineseApplied}}<br>中文: {{features .ChineseApplied}}{{end}}{{if .Warning}}<br>{{.Warning}}{{end}}{{if .Reused}}<br>{{.Reused}}{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// writeSyncReportHTML writes the report as a self-contained HTML file
+func writeSyncReportHTML(path string, report *SyncReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create report file: %v", err)
+	}
+
+	if err := syncReportTemplate.Execute(f, report); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to render report: %v", err)
+	}
+	// A failed flush on close, such as on a full disk, leaves the report truncated
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to write report file: %v", err)
+	}
+	return nil
+}
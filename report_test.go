@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSyncReportAddLines(t *testing.T) {
+	report := newSyncReport("Sync Report", "src", "dst")
+	match := &MatchDecision{LinesMatch: true}
+	applied := &LineFeatures{Bold: true}
+
+	report.addEnglishLine(1, 1, "Welcome", 1, "Welcome", match, applied, nil)
+	report.addChineseLine(2, 2, "欢迎", nil, applied, nil)
+	report.addEnglishLine(3, 2, "Amen", 3, "Amen!", &MatchDecision{LinesMatch: false}, nil, errors.New("quota exceeded"))
+	// A second Chinese line after a translated row starts a row of its own
+	report.addChineseLine(4, 4, "阿们", nil, nil, nil)
+	report.addChineseLine(5, 5, "阿们！", nil, nil, nil)
+
+	if len(report.Rows) != 3 {
+		t.Fatalf("got %d rows, want 3: %+v", len(report.Rows), report.Rows)
+	}
+	first := report.Rows[0]
+	if first.SourceText != "Welcome" || first.ChineseText != "欢迎" || first.ChineseLine != 2 || first.ChineseApplied != applied || first.Mismatch {
+		t.Errorf("first row = %+v", first)
+	}
+	second := report.Rows[1]
+	if !second.Mismatch || second.ChineseText != "阿们" || second.Warning != "Failed to apply formatting: quota exceeded" {
+		t.Errorf("second row = %+v", second)
+	}
+	third := report.Rows[2]
+	if third.TargetText != "" || third.ChineseText != "阿们！" || !third.Mismatch {
+		t.Errorf("Chinese-only row = %+v", third)
+	}
+	if got := report.MismatchCount(); got != 2 {
+		t.Errorf("MismatchCount() = %d, want 2", got)
+	}
+
+	var nilReport *SyncReport
+	nilReport.addEnglishLine(1, 1, "Welcome", 1, "Welcome", match, nil, nil)
+	nilReport.addChineseLine(2, 2, "欢迎", nil, nil, nil)
+	nilReport.setError(errors.New("boom"))
+}
+
+func TestBuildCompareReportRows(t *testing.T) {
+	source := buildDocumentLines("Welcome", "Call to Worship", "Benediction")
+	target := buildDocumentLines("Welcome", "欢迎", "An added line", "Benediction", "祝福")
+
+	report := newSyncReport("Compare Report", "src", "dst")
+	buildCompareReportRows(report, source, target)
+
+	want := []struct {
+		source   string
+		target   string
+		chinese  string
+		mismatch bool
+	}{
+		{"Welcome", "Welcome", "欢迎", false},
+		{"", "An added line", "", true},
+		{"Call to Worship", "", "", true},
+		{"Benediction", "Benediction", "祝福", false},
+	}
+	if len(report.Rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(report.Rows), len(want), report.Rows)
+	}
+	for i, w := range want {
+		row := report.Rows[i]
+		if row.SourceText != w.source || row.TargetText != w.target || row.ChineseText != w.chinese || row.Mismatch != w.mismatch {
+			t.Errorf("row %d = %q | %q | %q mismatch %v, want %q | %q | %q mismatch %v",
+				i, row.SourceText, row.TargetText, row.ChineseText, row.Mismatch, w.source, w.target, w.chinese, w.mismatch)
+		}
+	}
+}
+
+func TestWriteSyncReportHTML(t *testing.T) {
+	report := newSyncReport("Sync <Report>", "src", "dst")
+	report.addEnglishLine(1, 1, `Fish & <b>loaves</b>`, 1, `Fish & <b>loaves</b>`, &MatchDecision{LinesMatch: true}, nil, nil)
+	report.addChineseLine(2, 2, "五饼二鱼", nil, nil, nil)
+	report.setError(errors.New(`stopped at "<script>"`))
+
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := writeSyncReportHTML(path, report); err != nil {
+		t.Fatalf("writeSyncReportHTML() error = %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(b)
+	for _, want := range []string{"<title>Sync &lt;Report&gt;</title>", "Fish &amp; &lt;b&gt;loaves&lt;/b&gt;", "五饼二鱼", "&lt;script&gt;", "Mismatches: 0"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	if strings.Contains(html, "<b>loaves</b>") || strings.Contains(html, "<script>") {
+		t.Error("report contains unescaped line text")
+	}
+}
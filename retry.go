@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// Rate limiting of formatting writes, replacing the old fixed one-second delay
+const (
+	// FormattingMinInterval is the shortest gap the limiter allows between formatting writes
+	FormattingMinInterval = 200 * time.Millisecond
+	// FormattingMaxInterval is the longest gap the limiter backs off to after repeated throttling
+	FormattingMaxInterval = 30 * time.Second
+	// ThrottledMinInterval is the gap a limiter that wasn't spacing out calls starts at once the API throttles them
+	ThrottledMinInterval = 200 * time.Millisecond
+)
+
+// retryPolicy controls how Google API calls are retried on quota and transient errors
+type retryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+
+	// Limiter, when set, is told about throttling so that later calls slow down
+	Limiter *adaptiveLimiter
+
+	// sleep and jitter are replaceable in tests
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func() float64
+}
+
+// defaultRetryPolicy is shared by all Docs, Drive and Slides API calls except the formatting writes
+var defaultRetryPolicy = &retryPolicy{
+	MaxAttempts: 6,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+	Limiter:     apiLimiter,
+}
+
+// formattingRetryPolicy retries the per-line formatting writes of sync-format, so that only
+// their throttling slows down the formatting limiter
+var formattingRetryPolicy = &retryPolicy{
+	MaxAttempts: 6,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+	Limiter:     formattingLimiter,
+}
+
+var (
+	// formattingLimiter paces the per-line formatting writes of sync-format
+	formattingLimiter = newAdaptiveLimiter(time.Second, FormattingMinInterval, FormattingMaxInterval)
+	// apiLimiter paces document reads and writes, which run unspaced until the API throttles them
+	apiLimiter = newAdaptiveLimiter(0, 0, FormattingMaxInterval)
+)
+
+// retryableError reports whether err is a Google API error worth retrying, and the server's
+// requested delay from a Retry-After header if there was one
+func retryableError(err error) (bool, time.Duration) {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false, 0
+	}
+	switch apiErr.Code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable:
+		return true, parseRetryAfter(apiErr.Header.Get("Retry-After"), time.Now())
+	default:
+		return false, 0
+	}
+}
+
+// isThrottled reports whether err is a quota (429) error
+func isThrottled(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests
+}
+
+// parseRetryAfter interprets a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if d := when.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// backoff returns the delay before the given retry (0-based), growing exponentially with jitter
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << uint(attempt)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	jitter := rand.Float64
+	if p.jitter != nil {
+		jitter = p.jitter
+	}
+	// Equal jitter: keep half the delay and randomize the other half
+	return d/2 + time.Duration(jitter()*float64(d/2))
+}
+
+// do runs fn, retrying retryable Google API errors until it succeeds, fails permanently,
+// runs out of attempts or ctx is cancelled
+func (p *retryPolicy) do(ctx context.Context, op string, fn func() error) error {
+	return p.run(ctx, op, fn, true)
+}
+
+// doWrite runs a call that must not be applied twice, such as a batch that inserts text. Only
+// quota errors are retried: the API rejects those before doing anything, while a 500 or 503 can
+// come back after the write was committed.
+func (p *retryPolicy) doWrite(ctx context.Context, op string, fn func() error) error {
+	return p.run(ctx, op, fn, false)
+}
+
+// run implements do and doWrite; serverErrors says whether 5xx errors are retried
+func (p *retryPolicy) run(ctx context.Context, op string, fn func() error, serverErrors bool) error {
+	sleep := sleepContext
+	if p.sleep != nil {
+		sleep = p.sleep
+	}
+
+	var err error
+	for attempt := 0; attempt < p.MaxAttempts; attempt++ {
+		err = fn()
+		if err == nil {
+			if p.Limiter != nil {
+				p.Limiter.onSuccess()
+			}
+			return nil
+		}
+
+		retryable, retryAfter := retryableError(err)
+		if !serverErrors && !isThrottled(err) {
+			retryable = false
+		}
+		if !retryable || attempt == p.MaxAttempts-1 {
+			return err
+		}
+		if p.Limiter != nil && isThrottled(err) {
+			p.Limiter.onThrottle()
+		}
+
+		delay := p.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		slog.Warn("retrying Google API call", "op", op, "attempt", attempt+1, "delay", delay, "err", err)
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return sleepErr
+		}
+	}
+	return err
+}
+
+// sleepContext waits for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// adaptiveLimiter spaces out calls, widening the gap when the API throttles and
+// narrowing it again while calls succeed
+type adaptiveLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	min      time.Duration
+	max      time.Duration
+	last     time.Time
+}
+
+// newAdaptiveLimiter creates a limiter starting at the given interval
+func newAdaptiveLimiter(initial, min, max time.Duration) *adaptiveLimiter {
+	return &adaptiveLimiter{interval: initial, min: min, max: max}
+}
+
+// wait blocks until the current interval has passed since the previous call
+func (l *adaptiveLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	next := l.last.Add(l.interval)
+	now := time.Now()
+	if next.Before(now) {
+		next = now
+	}
+	l.last = next
+	l.mu.Unlock()
+
+	return sleepContext(ctx, time.Until(next))
+}
+
+// onSuccess shortens the interval by 10%
+func (l *adaptiveLimiter) onSuccess() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.interval = l.interval * 9 / 10
+	if l.interval < l.min {
+		l.interval = l.min
+	}
+}
+
+// onThrottle doubles the interval, starting from ThrottledMinInterval when calls weren't spaced out
+func (l *adaptiveLimiter) onThrottle() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.interval = max(l.interval*2, ThrottledMinInterval)
+	if l.interval > l.max {
+		l.interval = l.max
+	}
+	slog.Debug("rate limiter slowing down", "interval", l.interval)
+}
+
+// currentInterval returns the gap currently enforced between calls
+func (l *adaptiveLimiter) currentInterval() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.interval
+}
+
+// getDocument fetches a document, retrying quota and transient errors
+func getDocument(ctx context.Context, docsService *docs.Service, docID string) (*docs.Document, error) {
+	if err := apiLimiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	var doc *docs.Document
+	err := defaultRetryPolicy.do(ctx, "documents.get", func() error {
+		var err error
+		doc, err = docsService.Documents.Get(docID).Context(ctx).Do()
+		return err
+	})
+	return doc, err
+}
+
+// batchUpdateDocument applies a batch update, retrying quota errors, and transient errors too when
+// the batch only restyles existing text
+func batchUpdateDocument(ctx context.Context, docsService *docs.Service, docID string, req *docs.BatchUpdateDocumentRequest) (*docs.BatchUpdateDocumentResponse, error) {
+	if err := apiLimiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	return defaultRetryPolicy.batchUpdate(ctx, docsService, docID, req)
+}
+
+// batchUpdate applies a batch update under the policy. A batch that inserts or deletes content
+// is not retried on 5xx errors, which can come back after the batch was applied.
+func (p *retryPolicy) batchUpdate(ctx context.Context, docsService *docs.Service, docID string, req *docs.BatchUpdateDocumentRequest) (*docs.BatchUpdateDocumentResponse, error) {
+	var resp *docs.BatchUpdateDocumentResponse
+	call := func() error {
+		var err error
+		resp, err = docsService.Documents.BatchUpdate(docID, req).Context(ctx).Do()
+		return err
+	}
+	var err error
+	if isIdempotentBatch(req.Requests) {
+		err = p.do(ctx, "documents.batchUpdate", call)
+	} else {
+		err = p.doWrite(ctx, "documents.batchUpdate", call)
+	}
+	return resp, err
+}
+
+// isIdempotentBatch reports whether applying the requests twice leaves the doc as applying them
+// once does, which holds for requests that only set styles or bullets on existing text
+func isIdempotentBatch(requests []*docs.Request) bool {
+	for _, r := range requests {
+		if r.UpdateTextStyle == nil && r.UpdateParagraphStyle == nil && r.CreateParagraphBullets == nil &&
+			r.DeleteParagraphBullets == nil && r.UpdateDocumentStyle == nil && r.UpdateTableCellStyle == nil &&
+			r.UpdateTableColumnProperties == nil && r.UpdateTableRowStyle == nil {
+			return false
+		}
+	}
+	return true
+}
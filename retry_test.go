@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/googleapi"
+)
+
+func TestRetryableError(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "7")
+
+	tests := []struct {
+		name              string
+		err               error
+		expectedRetryable bool
+		expectedDelay     time.Duration
+	}{
+		{"Quota exceeded", &googleapi.Error{Code: 429}, true, 0},
+		{"Quota exceeded with Retry-After", &googleapi.Error{Code: 429, Header: header}, true, 7 * time.Second},
+		{"Internal error", &googleapi.Error{Code: 500}, true, 0},
+		{"Service unavailable", &googleapi.Error{Code: 503}, true, 0},
+		{"Permission denied", &googleapi.Error{Code: 403}, false, 0},
+		{"Not a Google API error", errors.New("boom"), false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryable, delay := retryableError(tt.err)
+			if retryable != tt.expectedRetryable {
+				t.Errorf("retryable = %v, want %v", retryable, tt.expectedRetryable)
+			}
+			if delay != tt.expectedDelay {
+				t.Errorf("delay = %v, want %v", delay, tt.expectedDelay)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 7, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{"Empty", "", 0},
+		{"Seconds", "30", 30 * time.Second},
+		{"HTTP date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"Date in the past", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"Garbage", "soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := parseRetryAfter(tt.value, now); result != tt.expected {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	var delays []time.Duration
+	limiter := newAdaptiveLimiter(time.Second, 100*time.Millisecond, 10*time.Second)
+	policy := &retryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+		Limiter:     limiter,
+		sleep: func(ctx context.Context, d time.Duration) error {
+			delays = append(delays, d)
+			return nil
+		},
+		jitter: func() float64 { return 1 },
+	}
+
+	calls := 0
+	err := policy.do(context.Background(), "test", func() error {
+		calls++
+		if calls < 3 {
+			return &googleapi.Error{Code: 429}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("do() returned error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+	expectedDelays := []time.Duration{time.Second, 2 * time.Second}
+	if len(delays) != len(expectedDelays) || delays[0] != expectedDelays[0] || delays[1] != expectedDelays[1] {
+		t.Errorf("delays = %v, want %v", delays, expectedDelays)
+	}
+	// Two throttles doubled the interval twice, then one success shortened it by 10%
+	if interval := limiter.currentInterval(); interval != 3600*time.Millisecond {
+		t.Errorf("limiter interval = %v, want 3.6s", interval)
+	}
+
+	calls = 0
+	err = policy.do(context.Background(), "test", func() error {
+		calls++
+		return &googleapi.Error{Code: 400}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("non-retryable error: calls = %d, err = %v; want 1 call and an error", calls, err)
+	}
+
+	calls = 0
+	err = policy.do(context.Background(), "test", func() error {
+		calls++
+		return &googleapi.Error{Code: 503}
+	})
+	if err == nil || calls != policy.MaxAttempts {
+		t.Errorf("persistent error: calls = %d, err = %v; want %d calls and an error", calls, err, policy.MaxAttempts)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &retryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, jitter: func() float64 { return 0 }}
+
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{0, 500 * time.Millisecond},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 5 * time.Second}, // capped at MaxDelay before jitter
+		{80, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if result := policy.backoff(tt.attempt); result != tt.expected {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, result, tt.expected)
+		}
+	}
+}
+
+func TestRetryPolicyDoWrite(t *testing.T) {
+	policy := &retryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+		sleep:       func(ctx context.Context, d time.Duration) error { return nil },
+	}
+
+	calls := 0
+	err := policy.doWrite(context.Background(), "test", func() error {
+		calls++
+		return &googleapi.Error{Code: 503}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("server error: calls = %d, err = %v; want 1 call and an error", calls, err)
+	}
+
+	calls = 0
+	err = policy.doWrite(context.Background(), "test", func() error {
+		calls++
+		if calls < 2 {
+			return &googleapi.Error{Code: 429}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("quota error: calls = %d, err = %v; want 2 calls and no error", calls, err)
+	}
+}
+
+func TestIsIdempotentBatch(t *testing.T) {
+	style := &docs.Request{UpdateTextStyle: &docs.UpdateTextStyleRequest{}}
+	paragraph := &docs.Request{UpdateParagraphStyle: &docs.UpdateParagraphStyleRequest{}}
+	insert := &docs.Request{InsertText: &docs.InsertTextRequest{}}
+	remove := &docs.Request{DeleteContentRange: &docs.DeleteContentRangeRequest{}}
+
+	tests := []struct {
+		name     string
+		requests []*docs.Request
+		expected bool
+	}{
+		{"Formatting only", []*docs.Request{style, paragraph}, true},
+		{"Inserts text", []*docs.Request{style, insert}, false},
+		{"Deletes text", []*docs.Request{remove}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := isIdempotentBatch(tt.requests); result != tt.expected {
+				t.Errorf("isIdempotentBatch() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAdaptiveLimiterStartsFromZero(t *testing.T) {
+	limiter := newAdaptiveLimiter(0, 0, 10*time.Second)
+	limiter.onThrottle()
+	if interval := limiter.currentInterval(); interval != ThrottledMinInterval {
+		t.Errorf("interval after throttle = %v, want %v", interval, ThrottledMinInterval)
+	}
+	limiter.onThrottle()
+	if interval := limiter.currentInterval(); interval != 2*ThrottledMinInterval {
+		t.Errorf("interval after second throttle = %v, want %v", interval, 2*ThrottledMinInterval)
+	}
+}
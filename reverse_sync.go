@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// baselineDir holds the formatting snapshots written after each successful sync
+const baselineDir = "sync_baselines"
+
+// ReverseAction describes what reverse sync does with one matched line pair
+type ReverseAction int
+
+const (
+	ReverseActionNone ReverseAction = iota
+	ReverseActionApply
+	ReverseActionSourceNewer
+	ReverseActionConflict
+)
+
+// String returns the action name used in structured log events
+func (a ReverseAction) String() string {
+	switch a {
+	case ReverseActionNone:
+		return "unchanged"
+	case ReverseActionApply:
+		return "apply_target_format"
+	case ReverseActionSourceNewer:
+		return "source_newer"
+	case ReverseActionConflict:
+		return "conflict"
+	default:
+		return "unknown"
+	}
+}
+
+// BaselineLine records the formatting of one source line at the time of the last sync
+type BaselineLine struct {
+	Key      string
+	Features *LineFeatures
+}
+
+// SyncBaseline is the formatting snapshot shared by a source/target document pair
+type SyncBaseline struct {
+	SourceDocID string
+	TargetDocID string
+	UpdatedAt   time.Time
+	Lines       []BaselineLine
+}
+
+// ReverseConflict describes a line whose formatting changed on both sides since the baseline
+type ReverseConflict struct {
+	SourceLine int
+	TargetLine int
+	Text       string
+	Source     *LineFeatures
+	Target     *LineFeatures
+	Baseline   *LineFeatures
+}
+
+// ReverseSyncResult summarizes a reverse synchronization run
+type ReverseSyncResult struct {
+	Applied     int
+	Unchanged   int
+	SourceNewer int
+	Conflicts   []ReverseConflict
+	Baseline    []BaselineLine
+}
+
+// baselinePath returns the file used to store the baseline for a document pair
+func baselinePath(sourceDocID, targetDocID string) string {
+	return filepath.Join(baselineDir, sourceDocID+"__"+targetDocID+".json")
+}
+
+// loadSyncBaseline reads the stored baseline for a document pair, returning nil if none exists
+func loadSyncBaseline(sourceDocID, targetDocID string) (*SyncBaseline, error) {
+	b, err := os.ReadFile(baselinePath(sourceDocID, targetDocID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var baseline SyncBaseline
+	if err := json.Unmarshal(b, &baseline); err != nil {
+		return nil, fmt.Errorf("unable to parse baseline: %v", err)
+	}
+	return &baseline, nil
+}
+
+// saveSyncBaseline writes the baseline for a document pair
+func saveSyncBaseline(baseline *SyncBaseline) error {
+	if err := os.MkdirAll(baselineDir, 0755); err != nil {
+		return err
+	}
+	baseline.UpdatedAt = time.Now()
+	b, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(baselinePath(baseline.SourceDocID, baseline.TargetDocID), b, 0644)
+}
+
+// buildBaselineLines captures the key and formatting of every non-empty line in a document
+func buildBaselineLines(doc *docs.Document) ([]BaselineLine, error) {
+	cursor := &DocumentCursor{Document: doc, ElementIndex: 0, LineIndex: 0}
+	var lines []BaselineLine
+	for {
+		lineInfo, err := getNextNonEmptyLine(cursor)
+		if err != nil {
+			if err.Error() == "end of document" {
+				break
+			}
+			return nil, err
+		}
+		lines = append(lines, BaselineLine{
+			Key:      generateLineKey(lineInfo.Text),
+			Features: extractLineFeatures(lineInfo.Element, lineInfo.TextRun, lineInfo.Text),
+		})
+	}
+	return lines, nil
+}
+
+// baselineFeaturesFor returns the baseline features for the given source line, if the key still matches
+func baselineFeaturesFor(baseline *SyncBaseline, sourceLineNum int, key string) *LineFeatures {
+	if baseline == nil || sourceLineNum < 1 || sourceLineNum > len(baseline.Lines) {
+		return nil
+	}
+	line := baseline.Lines[sourceLineNum-1]
+	if line.Key != key {
+		return nil
+	}
+	return line.Features
+}
+
+// floatPtrEqual compares optional point values, treating nil as zero like applyFormattingToRange does
+func floatPtrEqual(a, b *float64) bool {
+	var av, bv float64
+	if a != nil {
+		av = *a
+	}
+	if b != nil {
+		bv = *b
+	}
+	return av == bv
+}
+
+// formattingEqual compares the formatting properties that reverseFormattingRequests writes
+func formattingEqual(a, b *LineFeatures) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Alignment != b.Alignment || a.FontFamily != b.FontFamily {
+		return false
+	}
+	if !floatPtrEqual(a.FirstLineIndent, b.FirstLineIndent) || !floatPtrEqual(a.LeftIndent, b.LeftIndent) || !floatPtrEqual(a.RightIndent, b.RightIndent) {
+		return false
+	}
+	if !floatPtrEqual(a.FontSize, b.FontSize) {
+		return false
+	}
+	if a.Bold != b.Bold || a.Italic != b.Italic || a.Underline != b.Underline {
+		return false
+	}
+	if (a.TextColor == nil) != (b.TextColor == nil) {
+		return false
+	}
+	if a.TextColor != nil && *a.TextColor != *b.TextColor {
+		return false
+	}
+	return true
+}
+
+// decideReverseAction determines whether target formatting should be pushed back onto the source line
+func decideReverseAction(source, target, baseline *LineFeatures) ReverseAction {
+	if formattingEqual(source, target) {
+		return ReverseActionNone
+	}
+	// Without a baseline the target is treated as the reviewed version
+	if baseline == nil {
+		return ReverseActionApply
+	}
+
+	sourceChanged := !formattingEqual(source, baseline)
+	targetChanged := !formattingEqual(target, baseline)
+
+	switch {
+	case targetChanged && !sourceChanged:
+		return ReverseActionApply
+	case sourceChanged && !targetChanged:
+		return ReverseActionSourceNewer
+	default:
+		return ReverseActionConflict
+	}
+}
+
+// syncDocumentFormattingReverse pushes formatting fixes made in the bilingual target back to the English source
+func syncDocumentFormattingReverse(c *clients, sourceURL, targetURL string) {
+	sourceDocID := extractDocumentID(sourceURL)
+	targetDocID := extractDocumentID(targetURL)
+
+	if sourceDocID == "" {
+		logFatal("invalid source Google Docs URL, please provide a valid document URL", "url", sourceURL)
+	}
+	if targetDocID == "" {
+		logFatal("invalid target Google Docs URL, please provide a valid document URL", "url", targetURL)
+	}
+
+	slog.Info("reverse synchronizing document formatting", "source_doc_id", sourceDocID, "target_doc_id", targetDocID)
+
+	result, err := processDualDocumentsReverse(context.Background(), c.Docs, sourceDocID, targetDocID)
+	if err != nil {
+		logFatal("error reverse synchronizing documents", "err", err)
+	}
+
+	printReverseSyncReport(result)
+
+	if len(result.Conflicts) > 0 {
+		logFatal("reverse synchronization finished with conflicts", "conflicts", len(result.Conflicts))
+	}
+	slog.Info("reverse formatting synchronization completed successfully")
+}
+
+// processDualDocumentsReverse loads both documents and the stored baseline, then runs the reverse sync
+func processDualDocumentsReverse(ctx context.Context, docsService *docs.Service, sourceDocID, targetDocID string) (*ReverseSyncResult, error) {
+	sourceDoc, err := getDocument(ctx, docsService, sourceDocID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve source document: %v", err)
+	}
+
+	targetDoc, err := getDocument(ctx, docsService, targetDocID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve target document: %v", err)
+	}
+
+	baseline, err := loadSyncBaseline(sourceDocID, targetDocID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load sync baseline: %v", err)
+	}
+	if baseline == nil {
+		slog.Warn("no stored baseline found, target formatting will be treated as authoritative")
+	}
+
+	sourceCursor := &DocumentCursor{Document: sourceDoc, ElementIndex: 0, LineIndex: 0}
+	targetCursor := &DocumentCursor{Document: targetDoc, ElementIndex: 0, LineIndex: 0}
+
+	result, err := reverseSynchronizeDocuments(ctx, sourceCursor, targetCursor, sourceDocID, docsService, baseline)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveSyncBaseline(&SyncBaseline{SourceDocID: sourceDocID, TargetDocID: targetDocID, Lines: result.Baseline})
+	if err != nil {
+		return nil, fmt.Errorf("unable to save sync baseline: %v", err)
+	}
+
+	return result, nil
+}
+
+// reverseTextFields and reverseParagraphFields are every property formattingEqual compares, so that
+// a reverse write also clears what the target removed
+const (
+	reverseTextFields      = "bold,italic,underline,foregroundColor,fontSize,weightedFontFamily"
+	reverseParagraphFields = "alignment,indentStart,indentEnd,indentFirstLine"
+)
+
+// pointDimension returns an explicit point value, with a missing indent written as 0
+func pointDimension(value *float64) *docs.Dimension {
+	dimension := &docs.Dimension{Unit: "PT", ForceSendFields: []string{"Magnitude"}}
+	if value != nil {
+		dimension.Magnitude = *value
+	}
+	return dimension
+}
+
+// reverseFormattingRequests builds style updates that give a range exactly the features. Unlike
+// formattingRequests, false and missing values are written too.
+func reverseFormattingRequests(startIndex, endIndex int64, features *LineFeatures) []*docs.Request {
+	textStyle := &docs.TextStyle{
+		Bold:            features.Bold,
+		Italic:          features.Italic,
+		Underline:       features.Underline,
+		ForceSendFields: []string{"Bold", "Italic", "Underline"},
+	}
+	// A missing colour, size or font is cleared back to the paragraph's named style
+	if features.TextColor != nil {
+		textStyle.ForegroundColor = &docs.OptionalColor{Color: &docs.Color{RgbColor: &docs.RgbColor{
+			Red:   features.TextColor.Red,
+			Green: features.TextColor.Green,
+			Blue:  features.TextColor.Blue,
+		}}}
+	}
+	if features.FontSize != nil && *features.FontSize != 0 {
+		textStyle.FontSize = pointDimension(features.FontSize)
+	}
+	if features.FontFamily != "" {
+		textStyle.WeightedFontFamily = &docs.WeightedFontFamily{FontFamily: features.FontFamily}
+	}
+
+	return []*docs.Request{
+		{UpdateParagraphStyle: &docs.UpdateParagraphStyleRequest{
+			Range: &docs.Range{StartIndex: startIndex, EndIndex: endIndex},
+			ParagraphStyle: &docs.ParagraphStyle{
+				Alignment:       features.Alignment,
+				IndentStart:     pointDimension(features.LeftIndent),
+				IndentEnd:       pointDimension(features.RightIndent),
+				IndentFirstLine: pointDimension(features.FirstLineIndent),
+			},
+			Fields: reverseParagraphFields,
+		}},
+		{UpdateTextStyle: &docs.UpdateTextStyleRequest{
+			Range:     &docs.Range{StartIndex: startIndex, EndIndex: endIndex},
+			TextStyle: textStyle,
+			Fields:    reverseTextFields,
+		}},
+	}
+}
+
+// applyReverseFormatting writes the target's formatting onto a source line
+func applyReverseFormatting(ctx context.Context, docsService *docs.Service, docID string, startIndex, endIndex int64, features *LineFeatures) error {
+	// Pace formatting writes to stay within the API write quota
+	if err := formattingLimiter.wait(ctx); err != nil {
+		return err
+	}
+	logLineFeatures("applying target features to source range", startIndex, endIndex, features)
+
+	req := &docs.BatchUpdateDocumentRequest{Requests: reverseFormattingRequests(startIndex, endIndex, features)}
+	if _, err := formattingRetryPolicy.batchUpdate(ctx, docsService, docID, req); err != nil {
+		return fmt.Errorf("failed to apply formatting: %v", err)
+	}
+	return nil
+}
+
+// reverseSynchronizeDocuments walks the target's English lines and applies their formatting to the matching source lines
+func reverseSynchronizeDocuments(ctx context.Context, sourceCursor, targetCursor *DocumentCursor, sourceDocID string, docsService *docs.Service, baseline *SyncBaseline) (*ReverseSyncResult, error) {
+	result := &ReverseSyncResult{}
+	sourceLineNum := 0
+	targetLineNum := 0
+
+	// Walk the cursors the way synchronizeDocuments does: the source only advances after a Chinese
+	// target line, so consecutive English target lines are all matched against the same source line
+	lastProcessedWasChinese := false
+	sourceMatched := false // whether the current source line was already matched to a target line
+
+	sourceLineInfo, err := getNextNonEmptyLine(sourceCursor)
+	if err != nil {
+		return nil, fmt.Errorf("error reading first source line: %v", err)
+	}
+	sourceLineNum++
+
+	slog.Info("starting reverse document synchronization")
+
+	for {
+		if shouldAdvanceSourceCursor(lastProcessedWasChinese) {
+			sourceLineInfo, err = getNextNonEmptyLine(sourceCursor)
+			if err != nil {
+				if err.Error() == "end of document" {
+					slog.Info("reached end of source document", "source_line", sourceLineNum)
+					break
+				}
+				return nil, fmt.Errorf("error reading source document: %v", err)
+			}
+			sourceLineNum++
+			sourceMatched = false
+		}
+
+		targetLineInfo, err := getNextNonEmptyLine(targetCursor)
+		if err != nil {
+			if err.Error() == "end of document" {
+				slog.Info("reached end of target document", "target_line", targetLineNum)
+				break
+			}
+			return nil, fmt.Errorf("error reading target document: %v", err)
+		}
+		targetLineNum++
+
+		decision := AnalyzeLineMatch(sourceLineInfo.Text, targetLineInfo.Text, nil, lastProcessedWasChinese)
+		// Chinese and mixed lines are translations and have no source counterpart
+		if decision.LineType == LineTypeChinese || decision.LineType == LineTypeMixed {
+			lastProcessedWasChinese = true
+			continue
+		}
+		lastProcessedWasChinese = false
+
+		sourceKey := generateLineKey(sourceLineInfo.Text)
+		if !decision.LinesMatch {
+			return nil, &SyncError{
+				SourceLine: sourceLineNum,
+				TargetLine: targetLineNum,
+				SourceKey:  sourceKey,
+				TargetKey:  generateLineKey(targetLineInfo.Text),
+				Message:    "Line content mismatch",
+			}
+		}
+		// A repeated English line carries nothing new for a source line that was already handled
+		if sourceMatched {
+			logLineDecision(0, sourceLineNum, targetLineNum, decision, "skip_repeated_line", targetLineInfo.Text)
+			continue
+		}
+		sourceMatched = true
+
+		sourceFeatures := extractLineFeatures(sourceLineInfo.Element, sourceLineInfo.TextRun, sourceLineInfo.Text)
+		targetFeatures := extractLineFeatures(targetLineInfo.Element, targetLineInfo.TextRun, targetLineInfo.Text)
+		baseFeatures := baselineFeaturesFor(baseline, sourceLineNum, sourceKey)
+
+		newBaseline := sourceFeatures
+		action := decideReverseAction(sourceFeatures, targetFeatures, baseFeatures)
+		logLineDecision(0, sourceLineNum, targetLineNum, decision, action.String(), targetLineInfo.Text)
+		switch action {
+		case ReverseActionNone:
+			result.Unchanged++
+		case ReverseActionApply:
+			err := applyReverseFormatting(ctx, docsService, sourceDocID, sourceLineInfo.Element.StartIndex, sourceLineInfo.Element.EndIndex, targetFeatures)
+			if err != nil {
+				slog.Warn("failed to apply formatting", "source_line", sourceLineNum, "err", err)
+			} else {
+				result.Applied++
+				newBaseline = targetFeatures
+			}
+		case ReverseActionSourceNewer:
+			result.SourceNewer++
+			newBaseline = baseFeatures
+		case ReverseActionConflict:
+			result.Conflicts = append(result.Conflicts, ReverseConflict{
+				SourceLine: sourceLineNum,
+				TargetLine: targetLineNum,
+				Text:       sourceLineInfo.Text,
+				Source:     sourceFeatures,
+				Target:     targetFeatures,
+				Baseline:   baseFeatures,
+			})
+			newBaseline = baseFeatures
+		}
+
+		result.Baseline = append(result.Baseline, BaselineLine{Key: sourceKey, Features: newBaseline})
+	}
+
+	return result, nil
+}
+
+// printReverseSyncReport prints the outcome of a reverse sync, including each conflict
+func printReverseSyncReport(result *ReverseSyncResult) {
+	fmt.Println("\n=== Reverse Sync Summary ===")
+	fmt.Printf("Applied to source: %d\n", result.Applied)
+	fmt.Printf("Unchanged: %d\n", result.Unchanged)
+	fmt.Printf("Skipped (source changed since baseline): %d\n", result.SourceNewer)
+	fmt.Printf("Conflicts: %d\n", len(result.Conflicts))
+	for _, conflict := range result.Conflicts {
+		fmt.Printf("\n--- Conflict at source line %d / target line %d ---\n", conflict.SourceLine, conflict.TargetLine)
+		fmt.Printf("Text: %s\n", conflict.Text)
+		fmt.Print("[Baseline]\n" + formatLineFeatures(conflict.Baseline, conflict.SourceLine))
+		fmt.Print("[Source]\n" + formatLineFeatures(conflict.Source, conflict.SourceLine))
+		fmt.Print("[Target]\n" + formatLineFeatures(conflict.Target, conflict.TargetLine))
+	}
+	fmt.Println("============================")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/option"
+)
+
+func TestDecideReverseAction(t *testing.T) {
+	start := &LineFeatures{Alignment: "START"}
+	center := &LineFeatures{Alignment: "CENTER"}
+	boldStart := &LineFeatures{Alignment: "START", Bold: true}
+
+	tests := []struct {
+		name     string
+		source   *LineFeatures
+		target   *LineFeatures
+		baseline *LineFeatures
+		expected ReverseAction
+	}{
+		{"Identical formatting", start, start, start, ReverseActionNone},
+		{"Target fixed since baseline", start, center, start, ReverseActionApply},
+		{"Source changed since baseline", center, start, start, ReverseActionSourceNewer},
+		{"Both changed differently", boldStart, center, start, ReverseActionConflict},
+		{"Both changed the same way", center, center, start, ReverseActionNone},
+		{"No baseline", start, center, nil, ReverseActionApply},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := decideReverseAction(tt.source, tt.target, tt.baseline)
+			if result != tt.expected {
+				t.Errorf("decideReverseAction() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormattingEqual(t *testing.T) {
+	zero := 0.0
+	twelve := 12.0
+
+	tests := []struct {
+		name     string
+		a        *LineFeatures
+		b        *LineFeatures
+		expected bool
+	}{
+		{"Nil indent equals zero indent", &LineFeatures{LeftIndent: nil}, &LineFeatures{LeftIndent: &zero}, true},
+		{"Different font size", &LineFeatures{FontSize: &twelve}, &LineFeatures{}, false},
+		{"Text is ignored", &LineFeatures{Text: "a"}, &LineFeatures{Text: "b"}, true},
+		{"Different color", &LineFeatures{TextColor: &RGBColor{Red: 255}}, &LineFeatures{TextColor: &RGBColor{Blue: 255}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := formattingEqual(tt.a, tt.b); result != tt.expected {
+				t.Errorf("formattingEqual() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReverseSynchronizeDocuments(t *testing.T) {
+	sourceTexts := []string{"Welcome", "Amen", "Go in peace"}
+
+	tests := []struct {
+		name        string
+		target      []string
+		unchanged   int
+		sourceNewer int
+		mismatch    bool
+	}{
+		{"Alternating lines", []string{"Welcome", "欢迎", "Amen", "阿们", "Go in peace", "平安地去吧"}, 2, 1, false},
+		// Forward sync matches both English lines against the same source line
+		{"Repeated English line", []string{"Welcome", "欢迎", "Amen", "Amen", "阿们", "Go in peace", "平安地去吧"}, 2, 1, false},
+		{"English line without translation", []string{"Welcome", "Amen", "阿们", "Go in peace"}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The baseline is the formatting both docs had at the last sync; since then the
+			// source's last line was made italic
+			baseLines, err := buildBaselineLines(buildParagraphDocument(sourceTexts...))
+			if err != nil {
+				t.Fatalf("buildBaselineLines() error = %v", err)
+			}
+			baseline := &SyncBaseline{Lines: baseLines}
+			source := buildParagraphDocument(sourceTexts...)
+			source.Body.Content[2].Paragraph.Elements[0].TextRun.TextStyle.Italic = true
+
+			sourceCursor := &DocumentCursor{Document: source}
+			targetCursor := &DocumentCursor{Document: buildParagraphDocument(tt.target...)}
+			// No line needs a write, so no Docs service is required
+			result, err := reverseSynchronizeDocuments(context.Background(), sourceCursor, targetCursor, "src", nil, baseline)
+
+			var syncErr *SyncError
+			if tt.mismatch {
+				if !errors.As(err, &syncErr) || syncErr.SourceLine != 1 || syncErr.TargetLine != 2 {
+					t.Fatalf("error = %v, want a mismatch at source line 1 / target line 2", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reverseSynchronizeDocuments() error = %v", err)
+			}
+			if result.Unchanged != tt.unchanged || result.SourceNewer != tt.sourceNewer || result.Applied != 0 || len(result.Conflicts) != 0 {
+				t.Errorf("result = %+v", result)
+			}
+			if len(result.Baseline) != len(sourceTexts) {
+				t.Errorf("baseline has %d lines, want one per source line", len(result.Baseline))
+			}
+		})
+	}
+}
+
+func TestReverseSyncRemovedBold(t *testing.T) {
+	var batches []docs.BatchUpdateDocumentRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req docs.BatchUpdateDocumentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("unable to decode batch: %v", err)
+		}
+		batches = append(batches, req)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	docsService, err := docs.NewService(context.Background(), option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := []string{"Welcome", "Amen"}
+	baseLines, err := buildBaselineLines(buildParagraphDocument(texts...))
+	if err != nil {
+		t.Fatalf("buildBaselineLines() error = %v", err)
+	}
+	// The reviewer un-bolded the last line of the target
+	target := buildParagraphDocument("Welcome", "欢迎", "Amen", "阿们")
+	target.Body.Content[2].Paragraph.Elements[0].TextRun.TextStyle.Bold = false
+
+	result, err := reverseSynchronizeDocuments(context.Background(),
+		&DocumentCursor{Document: buildParagraphDocument(texts...)}, &DocumentCursor{Document: target},
+		"src", docsService, &SyncBaseline{Lines: baseLines})
+	if err != nil {
+		t.Fatalf("reverseSynchronizeDocuments() error = %v", err)
+	}
+	if result.Applied != 1 || result.Unchanged != 1 {
+		t.Errorf("result = %+v, want one applied and one unchanged line", result)
+	}
+	if got := result.Baseline[1].Features; got.Bold {
+		t.Errorf("baseline still records the line as bold: %+v", got)
+	}
+
+	if len(batches) != 1 {
+		t.Fatalf("got %d batch updates, want 1", len(batches))
+	}
+	var textStyle *docs.UpdateTextStyleRequest
+	for _, request := range batches[0].Requests {
+		if request.UpdateTextStyle != nil {
+			textStyle = request.UpdateTextStyle
+		}
+	}
+	if textStyle == nil || !strings.Contains(textStyle.Fields, "bold") || textStyle.TextStyle.Bold {
+		t.Fatalf("text style request = %+v, want bold cleared explicitly", textStyle)
+	}
+	if source := buildParagraphDocument(texts...).Body.Content[1]; textStyle.Range.StartIndex != source.StartIndex {
+		t.Errorf("request starts at %d, want the source line at %d", textStyle.Range.StartIndex, source.StartIndex)
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"embed"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/drive/v3"
+)
+
+// Script conversion targets
+const (
+	ScriptTraditional = "traditional"
+	ScriptSimplified  = "simplified"
+)
+
+// dictFS holds the bundled OpenCC conversion tables and pinyin readings, see dict/README.md
+//
+//go:embed dict/*.txt
+var dictFS embed.FS
+
+// scriptConverter converts Chinese text with a phrase table and a character table, preferring
+// the longest phrase that matches at each position
+type scriptConverter struct {
+	Characters map[rune]string
+	Phrases    map[string]string
+	maxPhrase  int // longest phrase, in runes
+}
+
+// scriptEdit summarizes the changes convert-script makes to a doc
+type scriptEdit struct {
+	Requests  []*docs.Request
+	Converted int // Chinese lines whose characters changed
+	Pinyin    int // pinyin lines added
+}
+
+// readDictionary reads a tab-separated bundled table, skipping comments and blank lines
+func readDictionary(name string) (map[string]string, error) {
+	f, err := dictFS.Open("dict/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "\t")
+		if !ok {
+			return nil, fmt.Errorf("unable to parse %s line %q", name, line)
+		}
+		entries[key] = value
+	}
+	return entries, scanner.Err()
+}
+
+// newScriptConverter builds the converter for a target script from the bundled tables
+func newScriptConverter(target string) (*scriptConverter, error) {
+	prefix := "st"
+	if target == ScriptSimplified {
+		prefix = "ts"
+	}
+	characters, err := readDictionary(prefix + "_characters.txt")
+	if err != nil {
+		return nil, err
+	}
+	phrases, err := readDictionary(prefix + "_phrases.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	conv := &scriptConverter{Characters: make(map[rune]string, len(characters)), Phrases: phrases}
+	for key, value := range characters {
+		runes := []rune(key)
+		if len(runes) != 1 {
+			return nil, fmt.Errorf("character table entry %q is not a single character", key)
+		}
+		conv.Characters[runes[0]] = value
+	}
+	for key := range phrases {
+		conv.maxPhrase = max(conv.maxPhrase, len([]rune(key)))
+	}
+	return conv, nil
+}
+
+// convert rewrites the Chinese characters of text in the target script
+func (c *scriptConverter) convert(text string) string {
+	runes := []rune(text)
+	var sb strings.Builder
+	for i := 0; i < len(runes); {
+		matched := false
+		for n := min(c.maxPhrase, len(runes)-i); n > 1; n-- {
+			if value, ok := c.Phrases[string(runes[i:i+n])]; ok {
+				sb.WriteString(value)
+				i += n
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if value, ok := c.Characters[runes[i]]; ok {
+			sb.WriteString(value)
+		} else {
+			sb.WriteRune(runes[i])
+		}
+		i++
+	}
+	return sb.String()
+}
+
+// pinyinTable holds the most common reading of every character, with tone marks, and the
+// readings of words whose characters are read differently
+type pinyinTable struct {
+	Characters map[rune]string
+	Phrases    map[string][]string
+	maxPhrase  int // longest phrase, in runes
+}
+
+// loadPinyinTable reads the bundled pinyin readings
+func loadPinyinTable() (*pinyinTable, error) {
+	characters, err := readDictionary("pinyin.txt")
+	if err != nil {
+		return nil, err
+	}
+	phrases, err := readDictionary("pinyin_phrases.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	table := &pinyinTable{Characters: make(map[rune]string, len(characters)), Phrases: make(map[string][]string, len(phrases))}
+	for key, value := range characters {
+		runes := []rune(key)
+		if len(runes) != 1 {
+			return nil, fmt.Errorf("pinyin table entry %q is not a single character", key)
+		}
+		table.Characters[runes[0]] = value
+	}
+	for key, value := range phrases {
+		syllables := strings.Fields(value)
+		if len(syllables) != len([]rune(key)) {
+			return nil, fmt.Errorf("pinyin phrase %q needs one syllable per character", key)
+		}
+		table.Phrases[key] = syllables
+		table.maxPhrase = max(table.maxPhrase, len(syllables))
+	}
+	return table, nil
+}
+
+// pinyinPunctuation replaces full-width punctuation in pinyin lines
+var pinyinPunctuation = strings.NewReplacer("，", ",", "。", ".", "！", "!", "？", "?", "：", ":", "；", ";", "、", ",", "（", " (", "）", ")", "《", "“", "》", "”")
+
+// syllables returns the reading of each rune of text, preferring the longest phrase that matches
+// at each position; runes without a reading get an empty string
+func (p *pinyinTable) syllables(runes []rune) []string {
+	readings := make([]string, len(runes))
+	for i := 0; i < len(runes); {
+		n := min(p.maxPhrase, len(runes)-i)
+		for ; n > 1; n-- {
+			if phrase, ok := p.Phrases[string(runes[i:i+n])]; ok {
+				copy(readings[i:], phrase)
+				break
+			}
+		}
+		if n <= 1 {
+			readings[i] = p.Characters[runes[i]]
+			n = 1
+		}
+		i += n
+	}
+	return readings
+}
+
+// annotate spells text in pinyin, one syllable per character separated by spaces. Punctuation and
+// characters without a reading are kept as they are.
+func (p *pinyinTable) annotate(text string) string {
+	runes := []rune(pinyinPunctuation.Replace(text))
+	var out []rune
+	lastSyllable := false
+	for i, syllable := range p.syllables(runes) {
+		r := runes[i]
+		switch {
+		case syllable != "":
+			if len(out) > 0 && !unicode.IsSpace(out[len(out)-1]) && !strings.ContainsRune("(“‘[", out[len(out)-1]) {
+				out = append(out, ' ')
+			}
+			out = append(out, []rune(syllable)...)
+		case lastSyllable && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			out = append(out, ' ', r)
+		default:
+			out = append(out, r)
+		}
+		lastSyllable = syllable != ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// pinyinFontSize returns the font size of a pinyin line: 60% of the Chinese text, rounded to
+// half a point, with 11pt assumed when the Chinese text has no explicit size
+func pinyinFontSize(style *docs.TextStyle) float64 {
+	size := 11.0
+	if style != nil && style.FontSize != nil && style.FontSize.Magnitude > 0 {
+		size = style.FontSize.Magnitude
+	}
+	return max(6, math.Round(size*0.6*2)/2)
+}
+
+// planScriptEdit builds the edits that convert every Chinese line of a bilingual doc and, when
+// pinyin is set, add a smaller pinyin line under it. A Chinese line already followed by its pinyin
+// line gets no second one. Lines are processed from the end of the doc backwards so that every
+// index refers to the unmodified doc.
+func planScriptEdit(doc *docs.Document, conv *scriptConverter, pinyin *pinyinTable) *scriptEdit {
+	edit := &scriptEdit{}
+	if doc.Body == nil {
+		return edit
+	}
+	content := doc.Body.Content
+	for i := len(content) - 1; i >= 0; i-- {
+		element := content[i]
+		if element.Paragraph == nil {
+			continue
+		}
+		text := paragraphText(element)
+		if text == "" || !isTranslationLine(classifyLineType(text)) {
+			continue
+		}
+
+		// Convert run by run so that each run keeps its text style. The pinyin is read from the
+		// same replacements, since a phrase split across runs can convert differently as a whole.
+		elements := element.Paragraph.Elements
+		replacements := make([]string, len(elements))
+		converted := text
+		if conv != nil {
+			var sb strings.Builder
+			for j, pe := range elements {
+				if pe.TextRun != nil {
+					replacements[j] = conv.convert(strings.TrimSuffix(pe.TextRun.Content, "\n"))
+					sb.WriteString(replacements[j])
+				}
+			}
+			converted = strings.TrimSpace(sb.String())
+		}
+
+		if pinyin != nil {
+			annotation := pinyin.annotate(converted)
+			var existing string
+			if i+1 < len(content) && content[i+1].Paragraph != nil {
+				existing = paragraphText(content[i+1])
+			}
+			if existing != annotation && existing != pinyin.annotate(text) {
+				var style *docs.TextStyle
+				tabs := ""
+				for _, pe := range element.Paragraph.Elements {
+					if pe.TextRun != nil {
+						style = pe.TextRun.TextStyle
+						tabs = strings.Repeat("\t", len(pe.TextRun.Content)-len(strings.TrimLeft(pe.TextRun.Content, "\t")))
+						break
+					}
+				}
+				insertAt := element.EndIndex - 1
+				start := insertAt + 1 + int64(len(tabs))
+				end := start + int64(len(utf16.Encode([]rune(annotation))))
+				edit.Requests = append(edit.Requests,
+					&docs.Request{InsertText: &docs.InsertTextRequest{Location: &docs.Location{Index: insertAt}, Text: "\n" + tabs + annotation}},
+					&docs.Request{UpdateTextStyle: &docs.UpdateTextStyleRequest{
+						Range:     &docs.Range{StartIndex: start, EndIndex: end},
+						TextStyle: &docs.TextStyle{FontSize: &docs.Dimension{Magnitude: pinyinFontSize(style), Unit: "PT"}},
+						Fields:    "fontSize",
+					}},
+				)
+				// The pinyin paragraph, up to and including its newline, is neither a heading nor a bullet
+				edit.Requests = append(edit.Requests, plainParagraphRequests(insertAt+1, end+1)...)
+				edit.Pinyin++
+			}
+		}
+
+		if converted == text {
+			continue
+		}
+		for j := len(elements) - 1; j >= 0; j-- {
+			run := elements[j].TextRun
+			if run == nil {
+				continue
+			}
+			old := strings.TrimSuffix(run.Content, "\n")
+			replacement := replacements[j]
+			if replacement == old {
+				continue
+			}
+			start := elements[j].StartIndex
+			edit.Requests = append(edit.Requests,
+				&docs.Request{DeleteContentRange: &docs.DeleteContentRangeRequest{Range: &docs.Range{StartIndex: start, EndIndex: start + int64(len(utf16.Encode([]rune(old))))}}},
+				&docs.Request{InsertText: &docs.InsertTextRequest{Location: &docs.Location{Index: start}, Text: replacement}},
+			)
+			if run.TextStyle != nil {
+				style := *run.TextStyle
+				style.Link = nil
+				edit.Requests = append(edit.Requests, &docs.Request{UpdateTextStyle: &docs.UpdateTextStyleRequest{
+					Range:     &docs.Range{StartIndex: start, EndIndex: start + int64(len(utf16.Encode([]rune(replacement))))},
+					TextStyle: &style,
+					Fields:    "*",
+				}})
+			}
+		}
+		edit.Converted++
+	}
+	return edit
+}
+
+// copyForPinyin copies a doc for pinyin lines and lets the service account edit the copy
+func copyForPinyin(ctx context.Context, c *clients, docID, title string) (*drive.File, error) {
+	srv, err := c.UserDrive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to set up Drive access: %v", err)
+	}
+	copied, err := copyInputDoc(ctx, srv, docID, title+" (pinyin)", "")
+	if err != nil {
+		return nil, err
+	}
+	if err := grantInputAccess(c, srv, copied.Id); err != nil {
+		return nil, fmt.Errorf("unable to grant access to the copy: %v", err)
+	}
+	return copied, nil
+}
+
+// convertScript converts the Chinese lines of a bilingual doc to another script and/or adds pinyin.
+// Pinyin lines read as English to every command that walks line pairs, so unless inPlace is set
+// they go into a copy of the doc, together with the conversion, and the original is left as it is.
+func convertScript(c *clients, docURL, target string, addPinyin, inPlace, dryRun bool) {
+	ctx := context.Background()
+	docID := extractDocumentID(docURL)
+	if docID == "" {
+		logFatal("could not extract document ID from URL", "url", docURL)
+	}
+
+	var conv *scriptConverter
+	var err error
+	if target != "" {
+		conv, err = newScriptConverter(target)
+		if err != nil {
+			logFatal("unable to load conversion dictionary", "err", err)
+		}
+	}
+	var pinyin *pinyinTable
+	if addPinyin {
+		pinyin, err = loadPinyinTable()
+		if err != nil {
+			logFatal("unable to load pinyin dictionary", "err", err)
+		}
+	}
+
+	doc, err := getDocument(ctx, c.Docs, docID)
+	if err != nil {
+		logFatal("unable to retrieve document", "err", err)
+	}
+	edit := planScriptEdit(doc, conv, pinyin)
+	slog.Info("planned script edits", "target", target, "converted_lines", edit.Converted, "pinyin_lines", edit.Pinyin)
+	if dryRun || len(edit.Requests) == 0 {
+		fmt.Printf("%d Chinese lines to convert, %d pinyin lines to add\n", edit.Converted, edit.Pinyin)
+		return
+	}
+
+	if addPinyin && !inPlace {
+		copied, err := copyForPinyin(ctx, c, docID, doc.Title)
+		if err != nil {
+			logFatal("unable to copy document", "err", err)
+		}
+		// Plan again against the copy so that every index refers to the doc being edited
+		copyDoc, err := getDocument(ctx, c.Docs, copied.Id)
+		if err != nil {
+			logFatal("unable to retrieve the copy", "doc_id", copied.Id, "err", err)
+		}
+		edit = planScriptEdit(copyDoc, conv, pinyin)
+		if _, err := batchUpdateDocument(ctx, c.Docs, copied.Id, &docs.BatchUpdateDocumentRequest{Requests: edit.Requests}); err != nil {
+			logFatal("unable to update the copy", "doc_id", copied.Id, "err", err)
+		}
+		fmt.Printf("Converted %d Chinese lines and added %d pinyin lines to a copy: %s\n", edit.Converted, edit.Pinyin, copied.WebViewLink)
+		return
+	}
+
+	if _, err := batchUpdateDocument(ctx, c.Docs, docID, &docs.BatchUpdateDocumentRequest{Requests: edit.Requests}); err != nil {
+		logFatal("unable to update document", "err", err)
+	}
+	fmt.Printf("Converted %d Chinese lines and added %d pinyin lines\n", edit.Converted, edit.Pinyin)
+}
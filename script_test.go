@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/api/docs/v1"
+)
+
+func TestScriptConverter(t *testing.T) {
+	toTraditional, err := newScriptConverter(ScriptTraditional)
+	if err != nil {
+		t.Fatalf("newScriptConverter(traditional) error = %v", err)
+	}
+	toSimplified, err := newScriptConverter(ScriptSimplified)
+	if err != nil {
+		t.Fatalf("newScriptConverter(simplified) error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		conv *scriptConverter
+		text string
+		want string
+	}{
+		{"Characters", toTraditional, "耶稣爱我，我知道。", "耶穌愛我，我知道。"},
+		{"Phrase overrides the character table", toTraditional, "赞美主，头发", "讚美主，頭髮"},
+		{"English and punctuation are kept", toTraditional, "John 3:16 约翰福音", "John 3:16 約翰福音"},
+		{"Back to Simplified", toSimplified, "耶穌愛我，我知道。", "耶稣爱我，我知道。"},
+		{"Traditional phrase", toSimplified, "一目瞭然", "一目了然"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.conv.convert(tt.text); got != tt.want {
+				t.Errorf("convert(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinyinAnnotate(t *testing.T) {
+	table, err := loadPinyinTable()
+	if err != nil {
+		t.Fatalf("loadPinyinTable() error = %v", err)
+	}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"耶稣爱我", "yē sū ài wǒ"},
+		{"耶穌愛我！", "yē sū ài wǒ!"},
+		{"神爱世人，甚至将他的独生子赐给他们", "shén ài shì rén, shèn zhì jiāng tā de dú shēng zǐ cì gěi tā men"},
+		{"约翰福音3:16（和合本）", "yuē hàn fú yīn 3:16 (hé hé běn)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := table.annotate(tt.text); got != tt.want {
+				t.Errorf("annotate(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinyinFontSize(t *testing.T) {
+	tests := []struct {
+		style *docs.TextStyle
+		want  float64
+	}{
+		{nil, 6.5},
+		{&docs.TextStyle{FontSize: &docs.Dimension{Magnitude: 14}}, 8.5},
+		{&docs.TextStyle{FontSize: &docs.Dimension{Magnitude: 8}}, 6},
+	}
+	for _, tt := range tests {
+		if got := pinyinFontSize(tt.style); got != tt.want {
+			t.Errorf("pinyinFontSize(%+v) = %v, want %v", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestPlanScriptEdit(t *testing.T) {
+	conv, err := newScriptConverter(ScriptTraditional)
+	if err != nil {
+		t.Fatalf("newScriptConverter() error = %v", err)
+	}
+	table, err := loadPinyinTable()
+	if err != nil {
+		t.Fatalf("loadPinyinTable() error = %v", err)
+	}
+	texts := []string{"Jesus loves me", "耶稣爱我", "", "Amen", "阿们"}
+	doc := buildParagraphDocument(texts...)
+
+	tests := []struct {
+		name      string
+		conv      *scriptConverter
+		pinyin    *pinyinTable
+		want      string
+		converted int
+		added     int
+	}{
+		{"Convert only", conv, nil, "Jesus loves me\n耶穌愛我\n\nAmen\n阿們\n", 2, 0},
+		{"Pinyin only", nil, table, "Jesus loves me\n耶稣爱我\nyē sū ài wǒ\n\nAmen\n阿们\nā men\n", 0, 2},
+		{"Convert and pinyin", conv, table, "Jesus loves me\n耶穌愛我\nyē sū ài wǒ\n\nAmen\n阿們\nā men\n", 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edit := planScriptEdit(doc, tt.conv, tt.pinyin)
+			text := ""
+			for _, s := range texts {
+				text += s + "\n"
+			}
+			if got := applyRequests(text, edit.Requests); got != tt.want {
+				t.Errorf("edited doc = %q, want %q", got, tt.want)
+			}
+			if edit.Converted != tt.converted || edit.Pinyin != tt.added {
+				t.Errorf("converted %d, added %d pinyin lines, want %d and %d", edit.Converted, edit.Pinyin, tt.converted, tt.added)
+			}
+		})
+	}
+
+	annotated := buildParagraphDocument("耶稣爱我", "yē sū ài wǒ")
+	if edit := planScriptEdit(annotated, conv, table); edit.Pinyin != 0 {
+		t.Errorf("added a second pinyin line: %+v", edit)
+	}
+}
+
+func TestPlanScriptEditSplitRuns(t *testing.T) {
+	conv, err := newScriptConverter(ScriptTraditional)
+	if err != nil {
+		t.Fatalf("newScriptConverter() error = %v", err)
+	}
+	table, err := loadPinyinTable()
+	if err != nil {
+		t.Fatalf("loadPinyinTable() error = %v", err)
+	}
+	// A bulleted Chinese line whose phrase is split over two runs converts run by run
+	doc := buildParagraphDocument("Hair", "头发")
+	line := doc.Body.Content[1]
+	line.Paragraph.Bullet = &docs.Bullet{ListId: "list"}
+	start := line.StartIndex
+	line.Paragraph.Elements = []*docs.ParagraphElement{
+		{StartIndex: start, EndIndex: start + 1, TextRun: &docs.TextRun{Content: "头", TextStyle: &docs.TextStyle{}}},
+		{StartIndex: start + 1, EndIndex: start + 3, TextRun: &docs.TextRun{Content: "发\n", TextStyle: &docs.TextStyle{Bold: true}}},
+	}
+
+	edit := planScriptEdit(doc, conv, table)
+	annotation := table.annotate("頭發")
+	want := "Hair\n頭發\n" + annotation + "\n"
+	if got := applyRequests("Hair\n头发\n", edit.Requests); got != want {
+		t.Errorf("edited doc = %q, want %q", got, want)
+	}
+
+	// The pinyin paragraph, and only it, is reset to plain text
+	pinyinStart := line.EndIndex
+	pinyinEnd := pinyinStart + int64(len([]rune(annotation))) + 1
+	var styled, unbulleted bool
+	for _, req := range edit.Requests {
+		if r := req.UpdateParagraphStyle; r != nil {
+			styled = r.Range.StartIndex == pinyinStart && r.Range.EndIndex == pinyinEnd && r.ParagraphStyle.NamedStyleType == "NORMAL_TEXT"
+		}
+		if r := req.DeleteParagraphBullets; r != nil {
+			unbulleted = r.Range.StartIndex == pinyinStart && r.Range.EndIndex == pinyinEnd
+		}
+	}
+	if !styled || !unbulleted {
+		t.Errorf("pinyin paragraph [%d, %d) is not reset: %+v", pinyinStart, pinyinEnd, edit.Requests)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// defaultBibleFile is read by e2e when application.yaml doesn't name a Bible data file
+const defaultBibleFile = "bible.json"
+
+// bibleBook is a book of the Bible with its Chinese Union Version name and accepted English abbreviations
+type bibleBook struct {
+	Name          string
+	Chinese       string
+	Abbreviations []string
+}
+
+// bibleBooks lists the 66 books in canonical order
+var bibleBooks = []bibleBook{
+	{"Genesis", "创世记", []string{"Gen", "Ge", "Gn"}},
+	{"Exodus", "出埃及记", []string{"Exod", "Exo", "Ex"}},
+	{"Leviticus", "利未记", []string{"Lev", "Le", "Lv"}},
+	{"Numbers", "民数记", []string{"Num", "Nu", "Nm"}},
+	{"Deuteronomy", "申命记", []string{"Deut", "Dt"}},
+	{"Joshua", "约书亚记", []string{"Josh", "Jos"}},
+	{"Judges", "士师记", []string{"Judg", "Jdg"}},
+	{"Ruth", "路得记", []string{"Rut", "Ru"}},
+	{"1 Samuel", "撒母耳记上", []string{"1 Sam", "1 Sa"}},
+	{"2 Samuel", "撒母耳记下", []string{"2 Sam", "2 Sa"}},
+	{"1 Kings", "列王纪上", []string{"1 Kgs", "1 Ki"}},
+	{"2 Kings", "列王纪下", []string{"2 Kgs", "2 Ki"}},
+	{"1 Chronicles", "历代志上", []string{"1 Chron", "1 Chr"}},
+	{"2 Chronicles", "历代志下", []string{"2 Chron", "2 Chr"}},
+	{"Ezra", "以斯拉记", []string{"Ezr"}},
+	{"Nehemiah", "尼希米记", []string{"Neh", "Ne"}},
+	{"Esther", "以斯帖记", []string{"Esth", "Est"}},
+	{"Job", "约伯记", []string{"Jb"}},
+	{"Psalms", "诗篇", []string{"Psalm", "Psa", "Ps", "Pss"}},
+	{"Proverbs", "箴言", []string{"Prov", "Pro", "Pr"}},
+	{"Ecclesiastes", "传道书", []string{"Eccles", "Eccl", "Ecc"}},
+	{"Song of Songs", "雅歌", []string{"Song of Solomon", "Song", "SS"}},
+	{"Isaiah", "以赛亚书", []string{"Isa"}},
+	{"Jeremiah", "耶利米书", []string{"Jer"}},
+	{"Lamentations", "耶利米哀歌", []string{"Lam"}},
+	{"Ezekiel", "以西结书", []string{"Ezek", "Eze"}},
+	{"Daniel", "但以理书", []string{"Dan", "Da"}},
+	{"Hosea", "何西阿书", []string{"Hos"}},
+	{"Joel", "约珥书", []string{"Joe"}},
+	{"Amos", "阿摩司书", []string{"Amo"}},
+	{"Obadiah", "俄巴底亚书", []string{"Obad", "Ob"}},
+	{"Jonah", "约拿书", []string{"Jon"}},
+	{"Micah", "弥迦书", []string{"Mic"}},
+	{"Nahum", "那鸿书", []string{"Nah"}},
+	{"Habakkuk", "哈巴谷书", []string{"Hab"}},
+	{"Zephaniah", "西番雅书", []string{"Zeph", "Zep"}},
+	{"Haggai", "哈该书", []string{"Hag"}},
+	{"Zechariah", "撒迦利亚书", []string{"Zech", "Zec"}},
+	{"Malachi", "玛拉基书", []string{"Mal"}},
+	{"Matthew", "马太福音", []string{"Matt", "Mat", "Mt"}},
+	{"Mark", "马可福音", []string{"Mrk", "Mk"}},
+	{"Luke", "路加福音", []string{"Luk", "Lk"}},
+	{"John", "约翰福音", []string{"Jn", "Jhn"}},
+	{"Acts", "使徒行传", []string{"Acts of the Apostles", "Act"}},
+	{"Romans", "罗马书", []string{"Rom", "Ro"}},
+	{"1 Corinthians", "哥林多前书", []string{"1 Cor", "1 Co"}},
+	{"2 Corinthians", "哥林多后书", []string{"2 Cor", "2 Co"}},
+	{"Galatians", "加拉太书", []string{"Gal"}},
+	{"Ephesians", "以弗所书", []string{"Eph"}},
+	{"Philippians", "腓立比书", []string{"Phil", "Php"}},
+	{"Colossians", "歌罗西书", []string{"Col"}},
+	{"1 Thessalonians", "帖撒罗尼迦前书", []string{"1 Thess", "1 Th"}},
+	{"2 Thessalonians", "帖撒罗尼迦后书", []string{"2 Thess", "2 Th"}},
+	{"1 Timothy", "提摩太前书", []string{"1 Tim", "1 Ti"}},
+	{"2 Timothy", "提摩太后书", []string{"2 Tim", "2 Ti"}},
+	{"Titus", "提多书", []string{"Tit"}},
+	{"Philemon", "腓利门书", []string{"Philem", "Phm"}},
+	{"Hebrews", "希伯来书", []string{"Heb"}},
+	{"James", "雅各书", []string{"Jas", "Jms"}},
+	{"1 Peter", "彼得前书", []string{"1 Pet", "1 Pe"}},
+	{"2 Peter", "彼得后书", []string{"2 Pet", "2 Pe"}},
+	{"1 John", "约翰一书", []string{"1 Jn", "1 Jhn"}},
+	{"2 John", "约翰二书", []string{"2 Jn", "2 Jhn"}},
+	{"3 John", "约翰三书", []string{"3 Jn", "3 Jhn"}},
+	{"Jude", "犹大书", []string{"Jud"}},
+	{"Revelation", "启示录", []string{"Rev", "Re"}},
+}
+
+// scriptureRef is a chapter:verse reference found in a line of text. EndChapter and EndVerse
+// equal Chapter and Verse for a single verse.
+type scriptureRef struct {
+	Book       *bibleBook
+	Chapter    int
+	Verse      int
+	EndChapter int
+	EndVerse   int
+	Start, End int // byte offsets of the reference in the line
+}
+
+// bibleVerse is one verse in both versions
+type bibleVerse struct {
+	English string `json:"en"`
+	Chinese string `json:"zh"`
+}
+
+// bibleData is the local Bible data file: canonical book name → "chapter:verse" → verse
+type bibleData map[string]map[string]bibleVerse
+
+// scriptureIssue is a reference on a translated line that could not be substituted
+type scriptureIssue struct {
+	Pair      int // 1-based index into the translation's line pairs
+	Reference string
+	Reason    string
+}
+
+// ambiguousBookNames are book names and abbreviations that are also ordinary English words, as in
+// "Song 10:45" or "Acts 2:00 pm". They only count as a book when written with a period, e.g.
+// "Song. 2:4", or in a longer form such as "Song of Songs" or "Acts of the Apostles".
+var ambiguousBookNames = map[string]bool{
+	"song": true, "ss": true, "acts": true, "act": true, "job": true, "jb": true,
+	"joe": true, "jud": true, "ex": true, "re": true, "pro": true, "mat": true,
+}
+
+// timeSuffixPattern matches what follows a clock time rather than a verse: seconds or am/pm
+var timeSuffixPattern = regexp.MustCompile(`(?i)^(:\d|\s*[ap]\.?m\b)`)
+
+var (
+	bookLookup       map[string]*bibleBook
+	scripturePattern *regexp.Regexp
+)
+
+func init() {
+	bookLookup = make(map[string]*bibleBook)
+	var names []string
+	for i := range bibleBooks {
+		book := &bibleBooks[i]
+		for _, name := range append([]string{book.Name}, book.Abbreviations...) {
+			for _, variant := range bookNameVariants(name) {
+				bookLookup[normalizeBookName(variant)] = book
+				names = append(names, variant)
+			}
+		}
+	}
+	// Longest names first so that "1 John" wins over "John" and "Philemon" over "Phil"
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	for i, name := range names {
+		names[i] = strings.ReplaceAll(regexp.QuoteMeta(name), " ", `\s*`)
+	}
+	scripturePattern = regexp.MustCompile(`(?i)\b(` + strings.Join(names, "|") + `)(\.)?\s*([1-9]\d*):([1-9]\d*)(?:\s*[-–]\s*(?:([1-9]\d*):)?([1-9]\d*))?\b`)
+}
+
+// bookNameVariants expands a leading book number into the spellings used in sermon notes, e.g. "1 Cor" → "I Cor", "First Cor"
+func bookNameVariants(name string) []string {
+	prefixes := map[string][]string{"1 ": {"I ", "First "}, "2 ": {"II ", "Second "}, "3 ": {"III ", "Third "}}
+	variants := []string{name}
+	for number, alternatives := range prefixes {
+		if rest, ok := strings.CutPrefix(name, number); ok {
+			for _, alt := range alternatives {
+				variants = append(variants, alt+rest)
+			}
+		}
+	}
+	return variants
+}
+
+// normalizeBookName lowercases a book name and drops spaces and periods for lookup
+func normalizeBookName(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r != ' ' && r != '.' && !unicode.IsSpace(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// findScriptureRefs returns the chapter:verse references in a line, e.g. "John 3:16" or "Rom. 8:28-30"
+func findScriptureRefs(line string) []scriptureRef {
+	var refs []scriptureRef
+	for _, m := range scripturePattern.FindAllStringSubmatchIndex(line, -1) {
+		name := normalizeBookName(line[m[2]:m[3]])
+		book := bookLookup[name]
+		if book == nil {
+			continue
+		}
+		// An ambiguous name needs its period, and a clock time is not a chapter and verse
+		if (ambiguousBookNames[name] && m[4] < 0) || timeSuffixPattern.MatchString(line[m[1]:]) {
+			continue
+		}
+		ref := scriptureRef{Book: book, Start: m[0], End: m[1]}
+		ref.Chapter, _ = strconv.Atoi(line[m[6]:m[7]])
+		ref.Verse, _ = strconv.Atoi(line[m[8]:m[9]])
+		ref.EndChapter, ref.EndVerse = ref.Chapter, ref.Verse
+		if m[10] >= 0 {
+			ref.EndChapter, _ = strconv.Atoi(line[m[10]:m[11]])
+		}
+		if m[12] >= 0 {
+			ref.EndVerse, _ = strconv.Atoi(line[m[12]:m[13]])
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// String formats the reference with the English book name
+func (r scriptureRef) String() string {
+	return r.Book.Name + " " + r.verseRange()
+}
+
+// Chinese formats the reference with the Chinese Union Version book name, e.g. 罗马书 8:28-30
+func (r scriptureRef) Chinese() string {
+	return r.Book.Chinese + " " + r.verseRange()
+}
+
+func (r scriptureRef) verseRange() string {
+	s := fmt.Sprintf("%d:%d", r.Chapter, r.Verse)
+	switch {
+	case r.EndChapter != r.Chapter:
+		s += fmt.Sprintf("-%d:%d", r.EndChapter, r.EndVerse)
+	case r.EndVerse != r.Verse:
+		s += fmt.Sprintf("-%d", r.EndVerse)
+	}
+	return s
+}
+
+// loadBibleData reads the local Bible data file; a missing file yields nil
+func loadBibleData(path string) (bibleData, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var data bibleData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("unable to parse Bible data %s: %v", path, err)
+	}
+	return data, nil
+}
+
+// passage returns the verses of a reference, or an error naming the first verse that is missing
+func (b bibleData) passage(ref scriptureRef) ([]bibleVerse, error) {
+	book := b[ref.Book.Name]
+	if book == nil {
+		return nil, fmt.Errorf("%s is not in the Bible data", ref.Book.Name)
+	}
+	if ref.EndChapter < ref.Chapter || (ref.EndChapter == ref.Chapter && ref.EndVerse < ref.Verse) {
+		return nil, fmt.Errorf("range ends before it starts")
+	}
+	var verses []bibleVerse
+	chapter, verse := ref.Chapter, ref.Verse
+	for chapter < ref.EndChapter || (chapter == ref.EndChapter && verse <= ref.EndVerse) {
+		v, ok := book[fmt.Sprintf("%d:%d", chapter, verse)]
+		if !ok {
+			// A range spanning chapters continues at verse 1 of the next chapter
+			if chapter < ref.EndChapter && verse > 1 {
+				chapter, verse = chapter+1, 1
+				continue
+			}
+			return nil, fmt.Errorf("%s %d:%d is not in the Bible data", ref.Book.Name, chapter, verse)
+		}
+		verses = append(verses, v)
+		verse++
+	}
+	return verses, nil
+}
+
+// wordsOf returns the lowercase words of English text
+func wordsOf(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+}
+
+// quotesPassage reports whether text reads like a quotation of the English passage:
+// at least three words, most of which appear in the passage
+func quotesPassage(text string, verses []bibleVerse) bool {
+	words := wordsOf(text)
+	if len(words) < 3 {
+		return false
+	}
+	passage := make(map[string]bool)
+	for _, v := range verses {
+		for _, w := range wordsOf(v.English) {
+			passage[w] = true
+		}
+	}
+	found := 0
+	for _, w := range words {
+		if passage[w] {
+			found++
+		}
+	}
+	return found*10 >= len(words)*7
+}
+
+// scriptureLine returns the Chinese line for an English line that is only a reference, or that
+// quotes the referenced passage with the reference at its start or end. ok is false for lines
+// that merely cite a reference inside other text.
+func scriptureLine(english string, ref scriptureRef, verses []bibleVerse) (string, bool) {
+	const wrappers = " \t()[]（）:：-–—,.;"
+	before := strings.TrimSpace(english[:ref.Start])
+	after := strings.TrimSpace(english[ref.End:])
+	var cuv strings.Builder
+	for _, v := range verses {
+		cuv.WriteString(strings.TrimSpace(v.Chinese))
+	}
+
+	switch {
+	case strings.Trim(before, wrappers) == "" && strings.Trim(after, wrappers) == "":
+		return ref.Chinese(), true
+	case strings.Trim(before, wrappers) == "" && quotesPassage(after, verses):
+		return ref.Chinese() + " " + cuv.String(), true
+	case strings.Trim(after, wrappers) == "" && quotesPassage(before, verses):
+		return cuv.String() + "（" + ref.Chinese() + "）", true
+	}
+	return "", false
+}
+
+// substituteScripture replaces the translation of every scripture line in bilingual LLM output
+// with the Chinese Union Version text from the Bible data, and reports references it could not
+// resolve. Lines that cite a reference inside other text keep the LLM translation.
+func substituteScripture(translation string, bible bibleData) (string, []scriptureIssue) {
+	lines := strings.Split(strings.ReplaceAll(translation, "\r\n", "\n"), "\n")
+	var out []string
+	var issues []scriptureIssue
+	pair := 0
+	var pending *scriptureRef // reference whose Chinese line still has to be replaced
+	replacement := ""
+
+	for _, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			out = append(out, raw)
+			continue
+		}
+		if !isTranslationLine(classifyLineType(line)) {
+			if pending != nil {
+				issues = append(issues, scriptureIssue{Pair: pair, Reference: pending.String(), Reason: "no Chinese line to replace"})
+			}
+			pair++
+			pending, replacement = nil, ""
+			for _, ref := range findScriptureRefs(line) {
+				verses, err := bible.passage(ref)
+				if err != nil {
+					issues = append(issues, scriptureIssue{Pair: pair, Reference: ref.String(), Reason: err.Error()})
+					continue
+				}
+				if text, ok := scriptureLine(line, ref, verses); ok {
+					ref := ref
+					pending, replacement = &ref, text
+					break
+				}
+			}
+			out = append(out, raw)
+			continue
+		}
+		if replacement == "" {
+			out = append(out, raw)
+			continue
+		}
+		// The first Chinese line is replaced and any continuation lines are dropped
+		if pending != nil {
+			out = append(out, replacement)
+			pending = nil
+		}
+	}
+	if pending != nil {
+		issues = append(issues, scriptureIssue{Pair: pair, Reference: pending.String(), Reason: "no Chinese line to replace"})
+	}
+	return strings.Join(out, "\n"), issues
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindScriptureRefs(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected []string
+	}{
+		{"Single verse", "John 3:16", []string{"John 3:16"}},
+		{"Abbreviation with period and range", "Rom. 8:28-30", []string{"Romans 8:28-30"}},
+		{"Numbered book", "Read 1 Cor 13:4–7 and 1John 4:8", []string{"1 Corinthians 13:4-7", "1 John 4:8"}},
+		{"Roman numeral", "II Tim. 3:16", []string{"2 Timothy 3:16"}},
+		{"Longer name wins", "Philemon 1:6 and Phil 4:13", []string{"Philemon 1:6", "Philippians 4:13"}},
+		{"Multi-word book", "Song of Songs 2:4", []string{"Song of Songs 2:4"}},
+		{"Cross-chapter range", "Rev 21:1-22:5", []string{"Revelation 21:1-22:5"}},
+		{"Time is not a reference", "Service at 10:30 on Sunday", nil},
+		{"Book name alone", "The Gospel of John", nil},
+		{"Ambiguous name with a period", "Song. 2:4 and Acts. 2:38", []string{"Song of Songs 2:4", "Acts 2:38"}},
+		{"Long form of an ambiguous name", "Acts of the Apostles 2:38", []string{"Acts 2:38"}},
+		{"Word before a time", "Sing the closing song 10:45", nil},
+		{"Capitalized word before a time", "Song 10:45", nil},
+		{"Time with am or pm", "Acts 2:00 pm", nil},
+		{"Time with a.m.", "Rom 8:28 a.m.", nil},
+		{"Time with seconds", "John 3:16:05", nil},
+		{"Verse zero", "Job 3:00", nil},
+		{"Job without a period", "Job 19:25", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, ref := range findScriptureRefs(tt.line) {
+				got = append(got, ref.String())
+			}
+			if strings.Join(got, ";") != strings.Join(tt.expected, ";") {
+				t.Errorf("findScriptureRefs(%q) = %v, want %v", tt.line, got, tt.expected)
+			}
+		})
+	}
+}
+
+func testBible() bibleData {
+	return bibleData{
+		"John": {
+			"3:16": {English: "For God so loved the world, that he gave his only begotten Son", Chinese: "神爱世人，甚至将他的独生子赐给他们，"},
+		},
+		"Romans": {
+			"8:28": {English: "And we know that all things work together for good", Chinese: "我们晓得万事都互相效力，"},
+			"8:29": {English: "For whom he did foreknow", Chinese: "因为他预先所知道的人，"},
+		},
+		"Revelation": {
+			"21:27": {English: "And there shall in no wise enter into it", Chinese: "凡不洁净的……总不得进那城；"},
+			"22:1":  {English: "And he shewed me a pure river", Chinese: "天使又指示我在城内街道当中一道生命水的河，"},
+		},
+	}
+}
+
+func TestBiblePassage(t *testing.T) {
+	bible := testBible()
+	tests := []struct {
+		name    string
+		line    string
+		verses  int
+		wantErr bool
+	}{
+		{"Single verse", "John 3:16", 1, false},
+		{"Range", "Rom 8:28-29", 2, false},
+		{"Cross-chapter range", "Rev 21:27-22:1", 2, false},
+		{"Missing verse", "Rom 8:28-30", 0, true},
+		{"Missing book", "Gen 1:1", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verses, err := bible.passage(findScriptureRefs(tt.line)[0])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("passage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(verses) != tt.verses {
+				t.Errorf("passage() returned %d verses, want %d", len(verses), tt.verses)
+			}
+		})
+	}
+}
+
+func TestSubstituteScripture(t *testing.T) {
+	translation := strings.Join([]string{
+		"John 3:16",
+		"约翰3章16节",
+		"",
+		"For God so loved the world, that he gave his only begotten Son (John 3:16)",
+		"神如此爱世界，",
+		"甚至赐下独生子。",
+		"Rom. 8:28 And we know that all things work together for good",
+		"我们知道万事互相效力",
+		"As Paul writes in Rom 8:28, God is at work.",
+		"正如保罗在罗马书8:28所写，神在工作。",
+		"Gen 1:1",
+		"起初",
+		"John 3:16",
+	}, "\n")
+
+	result, issues := substituteScripture(translation, testBible())
+
+	expected := strings.Join([]string{
+		"John 3:16",
+		"约翰福音 3:16",
+		"",
+		"For God so loved the world, that he gave his only begotten Son (John 3:16)",
+		"神爱世人，甚至将他的独生子赐给他们，（约翰福音 3:16）",
+		"Rom. 8:28 And we know that all things work together for good",
+		"罗马书 8:28 我们晓得万事都互相效力，",
+		"As Paul writes in Rom 8:28, God is at work.",
+		"正如保罗在罗马书8:28所写，神在工作。",
+		"Gen 1:1",
+		"起初",
+		"John 3:16",
+	}, "\n")
+	if result != expected {
+		t.Errorf("substituteScripture() =\n%s\nwant\n%s", result, expected)
+	}
+
+	if len(issues) != 2 {
+		t.Fatalf("issues = %+v, want 2", issues)
+	}
+	if issues[0].Pair != 5 || issues[0].Reference != "Genesis 1:1" {
+		t.Errorf("first issue = %+v, want the unresolved Genesis reference", issues[0])
+	}
+	if issues[1].Pair != 6 || issues[1].Reason != "no Chinese line to replace" {
+		t.Errorf("second issue = %+v, want the trailing line without translation", issues[1])
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// sharingConfig is the sharing section of application.yaml. Output docs are private to
+// their owner unless it grants access.
+type sharingConfig struct {
+	Users      []shareGrant `yaml:"users"`
+	Groups     []shareGrant `yaml:"groups"`
+	Domain     string       `yaml:"domain"`
+	DomainRole string       `yaml:"domain_role"`
+	Link       string       `yaml:"link"`   // reader, commenter or writer; empty or "private" for no link sharing
+	Notify     bool         `yaml:"notify"` // send Drive notification emails to users and groups
+}
+
+// shareGrant gives one user or group a role on the output doc
+type shareGrant struct {
+	Email string `yaml:"email"`
+	Role  string `yaml:"role"`
+}
+
+// shareRoles are the roles accepted in the sharing config
+var shareRoles = map[string]bool{"reader": true, "commenter": true, "writer": true}
+
+// driveRole validates a sharing role, defaulting to reader
+func driveRole(role string) (string, error) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if role == "" {
+		role = "reader"
+	}
+	if !shareRoles[role] {
+		return "", fmt.Errorf("unknown sharing role %q (expected reader, commenter or writer)", role)
+	}
+	return role, nil
+}
+
+// buildSharingPermissions turns the sharing config into the Drive permissions to create
+func buildSharingPermissions(cfg sharingConfig) ([]*drive.Permission, error) {
+	var perms []*drive.Permission
+
+	add := func(kind string, grants []shareGrant) error {
+		for _, g := range grants {
+			email := strings.TrimSpace(g.Email)
+			if email == "" {
+				return fmt.Errorf("sharing %s entry is missing an email", kind)
+			}
+			role, err := driveRole(g.Role)
+			if err != nil {
+				return fmt.Errorf("sharing %s %s: %v", kind, email, err)
+			}
+			perms = append(perms, &drive.Permission{Type: kind, Role: role, EmailAddress: email})
+		}
+		return nil
+	}
+	if err := add("user", cfg.Users); err != nil {
+		return nil, err
+	}
+	if err := add("group", cfg.Groups); err != nil {
+		return nil, err
+	}
+
+	if domain := strings.TrimSpace(cfg.Domain); domain != "" {
+		role, err := driveRole(cfg.DomainRole)
+		if err != nil {
+			return nil, fmt.Errorf("sharing domain %s: %v", domain, err)
+		}
+		perms = append(perms, &drive.Permission{Type: "domain", Role: role, Domain: domain, AllowFileDiscovery: false})
+	}
+
+	switch link := strings.ToLower(strings.TrimSpace(cfg.Link)); link {
+	case "", "private":
+	default:
+		role, err := driveRole(link)
+		if err != nil {
+			return nil, fmt.Errorf("sharing link: %v", err)
+		}
+		perms = append(perms, &drive.Permission{Type: "anyone", Role: role, AllowFileDiscovery: false})
+	}
+
+	return perms, nil
+}
+
+// applySharingPolicy creates the configured permissions on a newly created file
+func applySharingPolicy(ctx context.Context, srv *drive.Service, fileID string, cfg sharingConfig) error {
+	perms, err := buildSharingPermissions(cfg)
+	if err != nil {
+		return err
+	}
+	if len(perms) == 0 {
+		slog.Info("output doc is private to its owner", "doc_id", fileID)
+		return nil
+	}
+	for _, perm := range perms {
+		call := srv.Permissions.Create(fileID, perm).Fields("id").Context(ctx)
+		if perm.Type == "user" || perm.Type == "group" {
+			call = call.SendNotificationEmail(cfg.Notify)
+		}
+		if _, err := call.Do(); err != nil {
+			return fmt.Errorf("unable to share with %s %s: %w", perm.Type, describePermission(perm), err)
+		}
+		slog.Info("shared output doc", "type", perm.Type, "with", describePermission(perm), "role", perm.Role)
+	}
+	return nil
+}
+
+// describePermission names who a permission grants access to
+func describePermission(perm *drive.Permission) string {
+	switch perm.Type {
+	case "user", "group":
+		return perm.EmailAddress
+	case "domain":
+		return perm.Domain
+	default:
+		return "anyone with the link"
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestBuildSharingPermissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     sharingConfig
+		want    []string // type:role:target
+		wantErr bool
+	}{
+		{"Private by default", sharingConfig{}, nil, false},
+		{"Explicit private link", sharingConfig{Link: "private"}, nil, false},
+		{
+			name: "Users, groups, domain and link",
+			cfg: sharingConfig{
+				Users:      []shareGrant{{Email: "pastor@example.org", Role: "writer"}},
+				Groups:     []shareGrant{{Email: "translators@example.org", Role: "Commenter"}},
+				Domain:     "example.org",
+				DomainRole: "reader",
+				Link:       "reader",
+			},
+			want: []string{
+				"user:writer:pastor@example.org",
+				"group:commenter:translators@example.org",
+				"domain:reader:example.org",
+				"anyone:reader:anyone with the link",
+			},
+		},
+		{"Role defaults to reader", sharingConfig{Users: []shareGrant{{Email: "a@example.org"}}}, []string{"user:reader:a@example.org"}, false},
+		{"Unknown role", sharingConfig{Users: []shareGrant{{Email: "a@example.org", Role: "owner"}}}, nil, true},
+		{"Missing email", sharingConfig{Groups: []shareGrant{{Role: "reader"}}}, nil, true},
+		{"Unknown link role", sharingConfig{Link: "editor"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			perms, err := buildSharingPermissions(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildSharingPermissions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(perms) != len(tt.want) {
+				t.Fatalf("buildSharingPermissions() returned %d permissions, want %d", len(perms), len(tt.want))
+			}
+			for i, perm := range perms {
+				got := perm.Type + ":" + perm.Role + ":" + describePermission(perm)
+				if got != tt.want[i] {
+					t.Errorf("permission %d = %s, want %s", i, got, tt.want[i])
+				}
+				if perm.AllowFileDiscovery {
+					t.Errorf("permission %d allows file discovery", i)
+				}
+			}
+		})
+	}
+}
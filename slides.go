@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/api/slides/v1"
+)
+
+// defaultSlidePairs is the most line pairs put on one slide
+const defaultSlidePairs = 4
+
+// slidesClient is the part of the Slides API the slides command uses, so tests can fake it
+type slidesClient interface {
+	CreatePresentation(ctx context.Context, title string) (*slides.Presentation, error)
+	BatchUpdate(ctx context.Context, presentationID string, requests []*slides.Request) error
+}
+
+// googleSlides calls the Slides API as the OAuth user, so the user owns the presentation
+type googleSlides struct {
+	srv *slides.Service
+}
+
+// newGoogleSlides creates a Slides client authorized as the OAuth user
+func newGoogleSlides(ctx context.Context, c *clients) (*googleSlides, error) {
+	client, err := c.userHTTPClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	srv, err := slides.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Slides service: %v", err)
+	}
+	return &googleSlides{srv: srv}, nil
+}
+
+// CreatePresentation creates an empty presentation
+func (g *googleSlides) CreatePresentation(ctx context.Context, title string) (*slides.Presentation, error) {
+	var p *slides.Presentation
+	err := defaultRetryPolicy.doWrite(ctx, "presentations.create", func() error {
+		var err error
+		p, err = g.srv.Presentations.Create(&slides.Presentation{Title: title}).Context(ctx).Do()
+		return err
+	})
+	return p, err
+}
+
+// BatchUpdate applies requests to a presentation
+func (g *googleSlides) BatchUpdate(ctx context.Context, presentationID string, requests []*slides.Request) error {
+	return defaultRetryPolicy.doWrite(ctx, "presentations.batchUpdate", func() error {
+		_, err := g.srv.Presentations.BatchUpdate(presentationID, &slides.BatchUpdatePresentationRequest{Requests: requests}).Context(ctx).Do()
+		return err
+	})
+}
+
+// slideParagraph is a paragraph of a bilingual doc as the slide grouping sees it
+type slideParagraph struct {
+	Text    string
+	Heading int
+}
+
+// slideContent is one slide: the heading of its section, if any, and the line pairs shown on it
+type slideContent struct {
+	Title translationPair
+	Pairs []translationPair
+}
+
+// slideItem is a line pair with what came before it in the doc
+type slideItem struct {
+	Pair         translationPair
+	Heading      bool
+	BlanksBefore int
+	AfterBreak   bool // a separator line such as "——" came before the pair
+}
+
+// readSlideParagraphs returns the body paragraphs of a doc, including empty ones
+func readSlideParagraphs(doc *docs.Document) []slideParagraph {
+	var paragraphs []slideParagraph
+	if doc.Body == nil {
+		return nil
+	}
+	for _, element := range doc.Body.Content {
+		if element.Paragraph == nil {
+			continue
+		}
+		paragraphs = append(paragraphs, slideParagraph{Text: paragraphText(element), Heading: headingLevel(element)})
+	}
+	return paragraphs
+}
+
+// collectSlideItems pairs every English paragraph with the Chinese paragraphs after it and
+// records the blank lines and separators before each pair
+func collectSlideItems(paragraphs []slideParagraph) []slideItem {
+	var items []slideItem
+	blanks := 0
+	afterBreak := false
+	open := false // whether Chinese paragraphs still belong to the last pair
+	for _, p := range paragraphs {
+		switch {
+		case p.Text == "":
+			blanks++
+			open = false
+		case isTranslationLine(classifyLineType(p.Text)):
+			if open {
+				last := &items[len(items)-1].Pair
+				if last.Chinese == "" {
+					last.Chinese = p.Text
+				} else {
+					last.Chinese += "\n" + p.Text
+				}
+				continue
+			}
+			items = append(items, slideItem{Pair: translationPair{Chinese: p.Text}, Heading: p.Heading > 0, BlanksBefore: blanks, AfterBreak: afterBreak})
+			blanks, afterBreak, open = 0, false, true
+		case generateLineKey(p.Text) != "":
+			items = append(items, slideItem{Pair: translationPair{English: p.Text}, Heading: p.Heading > 0, BlanksBefore: blanks, AfterBreak: afterBreak})
+			blanks, afterBreak, open = 0, false, true
+		default:
+			afterBreak = true
+			open = false
+		}
+	}
+	return items
+}
+
+// groupSlides lays the pairs of a bilingual doc out on slides. A heading starts a new section
+// whose slides carry it as their title; a blank line or a separator starts a new slide, as does
+// reaching maxPairs. Docs that leave a blank line after every pair need two blank lines in a row
+// to start a new slide.
+func groupSlides(paragraphs []slideParagraph, maxPairs int) []slideContent {
+	if maxPairs < 1 {
+		maxPairs = defaultSlidePairs
+	}
+	items := collectSlideItems(paragraphs)
+
+	spaced := len(items) > 1
+	for _, item := range items[min(1, len(items)):] {
+		if item.BlanksBefore == 0 && !item.Heading && !item.AfterBreak {
+			spaced = false
+			break
+		}
+	}
+	breakBlanks := 1
+	if spaced {
+		breakBlanks = 2
+	}
+
+	var result []slideContent
+	var title translationPair
+	current := &slideContent{}
+	flush := func(keepEmpty bool) {
+		if len(current.Pairs) > 0 || (keepEmpty && current.Title != (translationPair{})) {
+			result = append(result, *current)
+		}
+		current = &slideContent{Title: title}
+	}
+	for _, item := range items {
+		if item.Heading {
+			// A heading with nothing under it still gets its own section slide
+			flush(true)
+			title = item.Pair
+			current = &slideContent{Title: title}
+			continue
+		}
+		if item.AfterBreak || item.BlanksBefore >= breakBlanks || len(current.Pairs) >= maxPairs {
+			flush(false)
+		}
+		current.Pairs = append(current.Pairs, item.Pair)
+	}
+	flush(true)
+	return result
+}
+
+// pairText returns the English and Chinese of a pair on separate lines
+func pairText(pair translationPair) string {
+	var lines []string
+	if pair.English != "" {
+		lines = append(lines, pair.English)
+	}
+	if pair.Chinese != "" {
+		lines = append(lines, pair.Chinese)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// placeholderMapping names the object created for a layout placeholder
+func placeholderMapping(placeholderType, objectID string) *slides.LayoutPlaceholderIdMapping {
+	return &slides.LayoutPlaceholderIdMapping{
+		LayoutPlaceholder: &slides.Placeholder{Type: placeholderType},
+		ObjectId:          objectID,
+	}
+}
+
+// buildSlideRequests creates a title slide followed by one slide per group. Every slide uses a
+// predefined layout: TITLE for the first, SECTION_HEADER for a heading with nothing under it and
+// TITLE_AND_BODY otherwise, with unused placeholders removed.
+func buildSlideRequests(title string, contents []slideContent) []*slides.Request {
+	var requests []*slides.Request
+	addSlide := func(id, layout string, mappings ...*slides.LayoutPlaceholderIdMapping) {
+		requests = append(requests, &slides.Request{CreateSlide: &slides.CreateSlideRequest{
+			ObjectId:              id,
+			SlideLayoutReference:  &slides.LayoutReference{PredefinedLayout: layout},
+			PlaceholderIdMappings: mappings,
+		}})
+	}
+	fill := func(objectID, text string) {
+		if text == "" {
+			requests = append(requests, &slides.Request{DeleteObject: &slides.DeleteObjectRequest{ObjectId: objectID}})
+			return
+		}
+		requests = append(requests, &slides.Request{InsertText: &slides.InsertTextRequest{ObjectId: objectID, Text: text}})
+	}
+
+	addSlide("slide_000", "TITLE", placeholderMapping("CENTERED_TITLE", "slide_000_title"), placeholderMapping("SUBTITLE", "slide_000_subtitle"))
+	fill("slide_000_title", title)
+	fill("slide_000_subtitle", "")
+
+	for i, content := range contents {
+		id := fmt.Sprintf("slide_%03d", i+1)
+		if len(content.Pairs) == 0 {
+			addSlide(id, "SECTION_HEADER", placeholderMapping("TITLE", id+"_title"))
+			fill(id+"_title", pairText(content.Title))
+			continue
+		}
+		addSlide(id, "TITLE_AND_BODY", placeholderMapping("TITLE", id+"_title"), placeholderMapping("BODY", id+"_body"))
+		fill(id+"_title", pairText(content.Title))
+		body := make([]string, len(content.Pairs))
+		for j, pair := range content.Pairs {
+			body[j] = pairText(pair)
+		}
+		fill(id+"_body", strings.Join(body, "\n"))
+	}
+	return requests
+}
+
+// createSlidesDeck creates a presentation holding the slides and removes the slide every new
+// presentation starts with
+func createSlidesDeck(ctx context.Context, client slidesClient, title string, contents []slideContent) (string, error) {
+	p, err := client.CreatePresentation(ctx, title)
+	if err != nil {
+		return "", fmt.Errorf("unable to create presentation: %v", err)
+	}
+	requests := buildSlideRequests(title, contents)
+	for _, slide := range p.Slides {
+		requests = append(requests, &slides.Request{DeleteObject: &slides.DeleteObjectRequest{ObjectId: slide.ObjectId}})
+	}
+	if err := client.BatchUpdate(ctx, p.PresentationId, requests); err != nil {
+		return "", fmt.Errorf("unable to add slides: %v", err)
+	}
+	return p.PresentationId, nil
+}
+
+// generateSlides creates a Slides presentation from a bilingual doc
+func generateSlides(c *clients, docURL, title string, maxPairs int) {
+	ctx := context.Background()
+	docID := extractDocumentID(docURL)
+	if docID == "" {
+		logFatal("could not extract document ID from URL", "url", docURL)
+	}
+	doc, err := getDocument(ctx, c.Docs, docID)
+	if err != nil {
+		logFatal("unable to retrieve document", "err", err)
+	}
+	if title == "" {
+		title = doc.Title
+	}
+
+	contents := groupSlides(readSlideParagraphs(doc), maxPairs)
+	if len(contents) == 0 {
+		logFatal("the document has no line pairs to put on slides", "doc_id", docID)
+	}
+	slidesService, err := newGoogleSlides(ctx, c)
+	if err != nil {
+		logFatal("unable to set up Slides access", "err", err)
+	}
+	id, err := createSlidesDeck(ctx, slidesService, title, contents)
+	if err != nil {
+		logFatal("unable to create slides", "err", err)
+	}
+	slog.Info("created presentation", "slides", len(contents)+1, "presentation_id", id)
+	fmt.Printf("Created %q with %d slides: https://docs.google.com/presentation/d/%s/edit\n", title, len(contents)+1, id)
+}
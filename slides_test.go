@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/slides/v1"
+)
+
+// fakeSlides records the calls made to the Slides API
+type fakeSlides struct {
+	created   []string
+	updates   [][]*slides.Request
+	updateErr error
+}
+
+func (f *fakeSlides) CreatePresentation(ctx context.Context, title string) (*slides.Presentation, error) {
+	f.created = append(f.created, title)
+	return &slides.Presentation{PresentationId: "deck1", Title: title, Slides: []*slides.Page{{ObjectId: "p"}}}, nil
+}
+
+func (f *fakeSlides) BatchUpdate(ctx context.Context, presentationID string, requests []*slides.Request) error {
+	f.updates = append(f.updates, requests)
+	return f.updateErr
+}
+
+func paragraphs(texts ...string) []slideParagraph {
+	var result []slideParagraph
+	for _, text := range texts {
+		result = append(result, slideParagraph{Text: text})
+	}
+	return result
+}
+
+func TestGroupSlides(t *testing.T) {
+	heading := func(text string) slideParagraph { return slideParagraph{Text: text, Heading: 1} }
+	tests := []struct {
+		name       string
+		paragraphs []slideParagraph
+		maxPairs   int
+		want       []slideContent
+	}{
+		{
+			name:       "Blank lines separate slides",
+			paragraphs: paragraphs("Welcome", "欢迎", "Amen", "阿们", "", "Go in peace", "平安地去吧"),
+			maxPairs:   4,
+			want: []slideContent{
+				{Pairs: []translationPair{{"Welcome", "欢迎"}, {"Amen", "阿们"}}},
+				{Pairs: []translationPair{{"Go in peace", "平安地去吧"}}},
+			},
+		},
+		{
+			name: "Headings title their section",
+			paragraphs: append([]slideParagraph{heading("Call to Worship"), heading("宣召")},
+				paragraphs("Come, let us worship", "来，我们敬拜", "——", "Let us bow down", "我们要屈身")...),
+			maxPairs: 4,
+			want: []slideContent{
+				{Title: translationPair{"Call to Worship", "宣召"}, Pairs: []translationPair{{"Come, let us worship", "来，我们敬拜"}}},
+				{Title: translationPair{"Call to Worship", "宣召"}, Pairs: []translationPair{{"Let us bow down", "我们要屈身"}}},
+			},
+		},
+		{
+			name:       "Section heading without pairs",
+			paragraphs: []slideParagraph{heading("Offering"), heading("Sermon"), {Text: "Grace"}, {Text: "恩典"}},
+			maxPairs:   4,
+			want: []slideContent{
+				{Title: translationPair{English: "Offering"}},
+				{Title: translationPair{English: "Sermon"}, Pairs: []translationPair{{"Grace", "恩典"}}},
+			},
+		},
+		{
+			name:       "Spaced docs need two blank lines",
+			paragraphs: paragraphs("One", "一", "", "Two", "二", "", "", "Three", "三", ""),
+			maxPairs:   4,
+			want: []slideContent{
+				{Pairs: []translationPair{{"One", "一"}, {"Two", "二"}}},
+				{Pairs: []translationPair{{"Three", "三"}}},
+			},
+		},
+		{
+			name:       "Long groups are split at maxPairs",
+			paragraphs: paragraphs("One", "一", "Two", "二", "Three", "三"),
+			maxPairs:   2,
+			want: []slideContent{
+				{Pairs: []translationPair{{"One", "一"}, {"Two", "二"}}},
+				{Pairs: []translationPair{{"Three", "三"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupSlides(tt.paragraphs, tt.maxPairs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupSlides() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateSlidesDeck(t *testing.T) {
+	contents := []slideContent{
+		{Pairs: []translationPair{{"Welcome", "欢迎"}, {"Amen", "阿们"}}},
+		{Title: translationPair{English: "Offering"}},
+	}
+	fake := &fakeSlides{}
+	id, err := createSlidesDeck(context.Background(), fake, "Sunday Service", contents)
+	if err != nil {
+		t.Fatalf("createSlidesDeck() error = %v", err)
+	}
+	if id != "deck1" || !reflect.DeepEqual(fake.created, []string{"Sunday Service"}) || len(fake.updates) != 1 {
+		t.Fatalf("id = %q, created = %v, %d updates", id, fake.created, len(fake.updates))
+	}
+
+	var layouts, inserted, deleted []string
+	for _, req := range fake.updates[0] {
+		switch {
+		case req.CreateSlide != nil:
+			layouts = append(layouts, req.CreateSlide.SlideLayoutReference.PredefinedLayout)
+		case req.InsertText != nil:
+			inserted = append(inserted, req.InsertText.ObjectId+"="+req.InsertText.Text)
+		case req.DeleteObject != nil:
+			deleted = append(deleted, req.DeleteObject.ObjectId)
+		}
+	}
+	if want := []string{"TITLE", "TITLE_AND_BODY", "SECTION_HEADER"}; !reflect.DeepEqual(layouts, want) {
+		t.Errorf("layouts = %v, want %v", layouts, want)
+	}
+	wantInserted := []string{"slide_000_title=Sunday Service", "slide_001_body=Welcome\n欢迎\nAmen\n阿们", "slide_002_title=Offering"}
+	if !reflect.DeepEqual(inserted, wantInserted) {
+		t.Errorf("inserted = %q, want %q", inserted, wantInserted)
+	}
+	// The unused subtitle and title placeholders, then the presentation's initial slide
+	if want := []string{"slide_000_subtitle", "slide_001_title", "p"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted = %v, want %v", deleted, want)
+	}
+
+	fake.updateErr = errors.New("quota exceeded")
+	if _, err := createSlidesDeck(context.Background(), fake, "Sunday Service", contents); err == nil {
+		t.Error("createSlidesDeck() ignored a failed batch update")
+	}
+}
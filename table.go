@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// tableRow is a line pair of the side-by-side layout with the source formatting of its English line
+type tableRow struct {
+	English  string
+	Chinese  string
+	Features *LineFeatures // nil when the English line is not in the source doc
+}
+
+// planTableRows lays out one row per translated pair, in translation order, taking each row's
+// formatting from the source line with the same key
+func planTableRows(sourceLines []DocumentLine, features []*LineFeatures, pairs []translationPair) []tableRow {
+	var pairLines []DocumentLine
+	var pairIndex []int
+	for i, pair := range pairs {
+		if pair.English == "" {
+			continue
+		}
+		pairLines = append(pairLines, DocumentLine{Number: i + 1, Text: pair.English, Key: generateLineKey(pair.English)})
+		pairIndex = append(pairIndex, i)
+	}
+	_, pairMatch := alignLineKeys(sourceLines, pairLines)
+
+	rowFeatures := make(map[int]*LineFeatures)
+	for j, source := range pairMatch {
+		if source >= 0 {
+			rowFeatures[pairIndex[j]] = features[source]
+		}
+	}
+
+	rows := make([]tableRow, len(pairs))
+	for i, pair := range pairs {
+		rows[i] = tableRow{English: pair.English, Chinese: pair.Chinese, Features: rowFeatures[i]}
+	}
+	return rows
+}
+
+// cellText returns the text of a row's English (column 0) or Chinese (column 1) cell, indented
+// with the source line's leading tabs
+func (r tableRow) cellText(column int) string {
+	text := r.English
+	if column == 1 {
+		text = r.Chinese
+	}
+	if text == "" || r.Features == nil || r.Features.LeadingTabs == 0 {
+		return text
+	}
+	tabs := strings.Repeat("\t", r.Features.LeadingTabs)
+	return tabs + strings.ReplaceAll(text, "\n", "\n"+tabs)
+}
+
+// bodyTable returns the first table in the document body
+func bodyTable(doc *docs.Document) *docs.Table {
+	if doc.Body == nil {
+		return nil
+	}
+	for _, element := range doc.Body.Content {
+		if element.Table != nil {
+			return element.Table
+		}
+	}
+	return nil
+}
+
+// checkTableShape verifies that the table has a row of two cells for every pair
+func checkTableShape(table *docs.Table, rows []tableRow) error {
+	if table == nil {
+		return fmt.Errorf("the output doc has no table")
+	}
+	if len(table.TableRows) != len(rows) {
+		return fmt.Errorf("the table has %d rows, want %d", len(table.TableRows), len(rows))
+	}
+	for i, row := range table.TableRows {
+		if len(row.TableCells) != 2 {
+			return fmt.Errorf("table row %d has %d cells, want 2", i+1, len(row.TableCells))
+		}
+		for _, cell := range row.TableCells {
+			if len(cell.Content) == 0 {
+				return fmt.Errorf("table row %d has an empty cell", i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// tableFillRequests inserts the text of every cell into an empty table. Cells are filled from
+// the end of the table backwards so earlier indices stay valid.
+func tableFillRequests(table *docs.Table, rows []tableRow) ([]*docs.Request, error) {
+	if err := checkTableShape(table, rows); err != nil {
+		return nil, err
+	}
+	var requests []*docs.Request
+	for r := len(rows) - 1; r >= 0; r-- {
+		for column := 1; column >= 0; column-- {
+			text := rows[r].cellText(column)
+			if text == "" {
+				continue
+			}
+			cell := table.TableRows[r].TableCells[column]
+			requests = append(requests, &docs.Request{InsertText: &docs.InsertTextRequest{
+				Location: &docs.Location{Index: cell.Content[0].StartIndex},
+				Text:     text,
+			}})
+		}
+	}
+	return requests, nil
+}
+
+// tableFormatRequests gives both cells of every row the formatting of the row's source line,
+// as sync-format does for a Chinese line and the English line before it
+func tableFormatRequests(table *docs.Table, rows []tableRow) ([]*docs.Request, error) {
+	if err := checkTableShape(table, rows); err != nil {
+		return nil, err
+	}
+	var requests []*docs.Request
+	for r, row := range rows {
+		if row.Features == nil {
+			continue
+		}
+		for _, cell := range table.TableRows[r].TableCells {
+			start := cell.Content[0].StartIndex
+			// Leave out the cell's final newline
+			end := cell.Content[len(cell.Content)-1].EndIndex - 1
+			if end <= start {
+				continue
+			}
+			requests = append(requests, formattingRequests(start, end, row.Features)...)
+		}
+	}
+	return requests, nil
+}
+
+// writeTranslationTable replaces the body of the output doc with a two-column table holding
+// one line pair per row, English left and Chinese right, formatted from the source doc
+func writeTranslationTable(ctx context.Context, docsService *docs.Service, sourceDocID, outputDocID, translation string) ([]tableRow, error) {
+	source, err := getDocument(ctx, docsService, sourceDocID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve source document: %w", err)
+	}
+	sourceLines, features, err := collectSourceLines(source)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source document: %w", err)
+	}
+	rows := planTableRows(sourceLines, features, parseTranslationPairs(translation))
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("the translation has no lines")
+	}
+
+	output, err := getDocument(ctx, docsService, outputDocID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve output document: %w", err)
+	}
+	var requests []*docs.Request
+	if end := bodyEndIndex(output) - 1; end > 1 {
+		requests = append(requests, &docs.Request{DeleteContentRange: &docs.DeleteContentRangeRequest{Range: &docs.Range{StartIndex: 1, EndIndex: end}}})
+	}
+	requests = append(requests, &docs.Request{InsertTable: &docs.InsertTableRequest{
+		Rows:                 int64(len(rows)),
+		Columns:              2,
+		EndOfSegmentLocation: &docs.EndOfSegmentLocation{},
+	}})
+	if _, err := batchUpdateDocument(ctx, docsService, outputDocID, &docs.BatchUpdateDocumentRequest{Requests: requests}); err != nil {
+		return nil, fmt.Errorf("unable to insert table: %w", err)
+	}
+
+	// Cell indices are only known once the table exists, and again once the text is in
+	for _, build := range []func(*docs.Table, []tableRow) ([]*docs.Request, error){tableFillRequests, tableFormatRequests} {
+		output, err = getDocument(ctx, docsService, outputDocID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve output document: %w", err)
+		}
+		requests, err := build(bodyTable(output), rows)
+		if err != nil {
+			return nil, err
+		}
+		if len(requests) == 0 {
+			continue
+		}
+		if _, err := batchUpdateDocument(ctx, docsService, outputDocID, &docs.BatchUpdateDocumentRequest{Requests: requests}); err != nil {
+			return nil, fmt.Errorf("batch update failed: %w", err)
+		}
+	}
+	return rows, nil
+}
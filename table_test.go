@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// buildTable returns a table whose cells hold the given texts, starting at index start
+func buildTable(start int64, cells [][2]string) *docs.Table {
+	table := &docs.Table{}
+	index := start
+	for _, texts := range cells {
+		row := &docs.TableRow{}
+		index++ // row start
+		for _, text := range texts {
+			index++ // cell start
+			length := int64(len([]rune(text))) + 1
+			row.TableCells = append(row.TableCells, &docs.TableCell{Content: []*docs.StructuralElement{
+				{StartIndex: index, EndIndex: index + length, Paragraph: &docs.Paragraph{}},
+			}})
+			index += length
+		}
+		table.TableRows = append(table.TableRows, row)
+	}
+	return table
+}
+
+func TestPlanTableRows(t *testing.T) {
+	source := buildDocumentLines("Welcome", "Call to Worship", "Amen")
+	features := []*LineFeatures{{Bold: true}, {Alignment: "CENTER"}, {Italic: true}}
+	pairs := []translationPair{
+		{English: "Welcome", Chinese: "欢迎"},
+		{English: "An added line", Chinese: "新加的一行"},
+		{English: "Call to Worship", Chinese: "宣召"},
+		{Chinese: "阿们"},
+	}
+
+	rows := planTableRows(source, features, pairs)
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want 4", len(rows))
+	}
+	if rows[0].Features != features[0] || rows[2].Features != features[1] {
+		t.Errorf("matched rows did not get the source formatting: %+v", rows)
+	}
+	if rows[1].Features != nil || rows[3].Features != nil {
+		t.Errorf("unmatched rows got formatting: %+v", rows)
+	}
+	if rows[3].English != "" || rows[3].Chinese != "阿们" {
+		t.Errorf("Chinese-only row = %+v", rows[3])
+	}
+}
+
+func TestTableRowCellText(t *testing.T) {
+	row := tableRow{English: "Point", Chinese: "要点\n第二行", Features: &LineFeatures{LeadingTabs: 1}}
+	if got := row.cellText(0); got != "\tPoint" {
+		t.Errorf("English cell = %q", got)
+	}
+	if got := row.cellText(1); got != "\t要点\n\t第二行" {
+		t.Errorf("Chinese cell = %q", got)
+	}
+	if got := (tableRow{English: "Plain"}).cellText(0); got != "Plain" {
+		t.Errorf("unformatted cell = %q", got)
+	}
+}
+
+func TestTableFillRequests(t *testing.T) {
+	rows := []tableRow{{English: "Welcome", Chinese: "欢迎"}, {Chinese: "阿们"}}
+	table := buildTable(2, [][2]string{{"", ""}, {"", ""}})
+
+	requests, err := tableFillRequests(table, rows)
+	if err != nil {
+		t.Fatalf("tableFillRequests() error = %v", err)
+	}
+	want := []struct {
+		index int64
+		text  string
+	}{
+		{table.TableRows[1].TableCells[1].Content[0].StartIndex, "阿们"},
+		{table.TableRows[0].TableCells[1].Content[0].StartIndex, "欢迎"},
+		{table.TableRows[0].TableCells[0].Content[0].StartIndex, "Welcome"},
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("got %d requests, want %d", len(requests), len(want))
+	}
+	for i, w := range want {
+		insert := requests[i].InsertText
+		if insert.Location.Index != w.index || insert.Text != w.text {
+			t.Errorf("request %d inserts %q at %d, want %q at %d", i, insert.Text, insert.Location.Index, w.text, w.index)
+		}
+	}
+
+	if _, err := tableFillRequests(table, rows[:1]); err == nil {
+		t.Error("tableFillRequests() accepted a table with the wrong number of rows")
+	}
+}
+
+func TestTableFormatRequests(t *testing.T) {
+	rows := []tableRow{
+		{English: "Welcome", Chinese: "欢迎", Features: &LineFeatures{Bold: true, Alignment: "CENTER"}},
+		{English: "Extra", Chinese: "额外"},
+	}
+	table := buildTable(2, [][2]string{{"Welcome", "欢迎"}, {"Extra", "额外"}})
+
+	requests, err := tableFormatRequests(table, rows)
+	if err != nil {
+		t.Fatalf("tableFormatRequests() error = %v", err)
+	}
+	// A paragraph and a text style update for each cell of the formatted row
+	if len(requests) != 4 {
+		t.Fatalf("got %d requests, want 4", len(requests))
+	}
+	english := table.TableRows[0].TableCells[0].Content[0]
+	r := requests[0].UpdateParagraphStyle
+	if r == nil || r.Range.StartIndex != english.StartIndex || r.Range.EndIndex != english.EndIndex-1 || r.ParagraphStyle.Alignment != "CENTER" {
+		t.Errorf("English cell paragraph style = %+v", requests[0])
+	}
+	if ts := requests[3].UpdateTextStyle; ts == nil || !ts.TextStyle.Bold {
+		t.Errorf("Chinese cell text style = %+v", requests[3])
+	}
+}
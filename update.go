@@ -0,0 +1,531 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/drive/v3"
+)
+
+// sourceSnapshotFile holds the English source lines a run translated, one per line
+const sourceSnapshotFile = "source.txt"
+
+// sourceHunk is a run of source lines removed and added between two unchanged lines.
+// A hunk with both removed and added lines is a change.
+type sourceHunk struct {
+	Removed  []int // indices into the old lines
+	Added    []int // indices into the new lines
+	Previous int   // index of the unchanged old line before the hunk, or -1 at the start
+	Next     int   // index of the unchanged old line after the hunk, or -1 at the end
+}
+
+// bilingualPair is an English paragraph of a bilingual doc and the Chinese paragraphs after it
+type bilingualPair struct {
+	Line       DocumentLine
+	Elements   []*docs.StructuralElement // the English paragraph first
+	SpacingEnd int64                     // end of the empty paragraphs following the pair, or of the pair itself
+}
+
+// updatePlan is the batch of edits that brings a bilingual doc in line with the edited source
+type updatePlan struct {
+	Requests []*docs.Request
+	Inserted []int          // indices of the new source lines inserted into the doc
+	Missing  []DocumentLine // removed source lines that were not found in the bilingual doc
+}
+
+// saveSourceSnapshot records the source lines of a run's input doc so that update can find later edits
+func saveSourceSnapshot(ctx context.Context, docsService *docs.Service, m *runManifest) error {
+	doc, err := getDocument(ctx, docsService, m.InputDocID)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve input document: %v", err)
+	}
+	lines, _, err := collectSourceLines(doc)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(runDir(m.RunID), sourceSnapshotFile)
+	if err := writeSourceSnapshot(path, lines); err != nil {
+		return err
+	}
+	m.SourceSnapshot = path
+	return nil
+}
+
+// writeSourceSnapshot writes the text of the source lines, one per line
+func writeSourceSnapshot(path string, lines []DocumentLine) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(line.Text)
+		sb.WriteString("\n")
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// readSourceSnapshot reads the source lines written by writeSourceSnapshot
+func readSourceSnapshot(path string) ([]DocumentLine, error) {
+	text, err := readTextFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lines []DocumentLine
+	for _, raw := range strings.Split(text, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		lines = append(lines, DocumentLine{
+			Number: len(lines) + 1,
+			Text:   line,
+			Type:   classifyLineType(line),
+			Key:    generateLineKey(line),
+		})
+	}
+	return lines, nil
+}
+
+// collectSourceLines reads every non-empty line of the source doc with the formatting sync-format would apply
+func collectSourceLines(doc *docs.Document) ([]DocumentLine, []*LineFeatures, error) {
+	cursor := &DocumentCursor{Document: doc, ElementIndex: 0, LineIndex: 0}
+	var lines []DocumentLine
+	var features []*LineFeatures
+	for {
+		lineInfo, err := getNextNonEmptyLine(cursor)
+		if err != nil {
+			if err.Error() == "end of document" {
+				break
+			}
+			return nil, nil, err
+		}
+		lines = append(lines, DocumentLine{
+			Number: len(lines) + 1,
+			Text:   lineInfo.Text,
+			Type:   classifyLineType(lineInfo.Text),
+			Key:    generateLineKey(lineInfo.Text),
+		})
+		features = append(features, extractLineFeatures(lineInfo.Element, lineInfo.TextRun, lineInfo.Text))
+	}
+	return lines, features, nil
+}
+
+// diffSourceLines aligns the snapshot with the current source lines and groups the differences into hunks
+func diffSourceLines(oldLines, newLines []DocumentLine) []sourceHunk {
+	oldMatch, newMatch := alignLineKeys(oldLines, newLines)
+	var hunks []sourceHunk
+	i, j, previous := 0, 0, -1
+	for i < len(oldLines) || j < len(newLines) {
+		hunk := sourceHunk{Previous: previous, Next: -1}
+		for ; i < len(oldLines) && oldMatch[i] < 0; i++ {
+			hunk.Removed = append(hunk.Removed, i)
+		}
+		for ; j < len(newLines) && newMatch[j] < 0; j++ {
+			hunk.Added = append(hunk.Added, j)
+		}
+		if i < len(oldLines) {
+			hunk.Next = i
+		}
+		if len(hunk.Removed) > 0 || len(hunk.Added) > 0 {
+			hunks = append(hunks, hunk)
+		}
+		if i >= len(oldLines) || j >= len(newLines) {
+			break
+		}
+		// oldLines[i] and newLines[j] are partners in the alignment
+		previous = i
+		i++
+		j++
+	}
+	return hunks
+}
+
+// paragraphText returns the trimmed text of a paragraph element
+func paragraphText(element *docs.StructuralElement) string {
+	var sb strings.Builder
+	for _, pe := range element.Paragraph.Elements {
+		if pe.TextRun != nil {
+			sb.WriteString(pe.TextRun.Content)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// collectBilingualPairs groups the paragraphs of a bilingual doc into English/Chinese pairs
+func collectBilingualPairs(doc *docs.Document) []bilingualPair {
+	var pairs []bilingualPair
+	open := false // whether Chinese paragraphs still belong to the last pair
+	if doc.Body == nil {
+		return nil
+	}
+	for _, element := range doc.Body.Content {
+		if element.Paragraph == nil {
+			open = false
+			continue
+		}
+		text := paragraphText(element)
+		switch {
+		case text == "":
+			if len(pairs) > 0 && pairs[len(pairs)-1].SpacingEnd == element.StartIndex {
+				pairs[len(pairs)-1].SpacingEnd = element.EndIndex
+			}
+			open = false
+		case isTranslationLine(classifyLineType(text)):
+			if open {
+				last := &pairs[len(pairs)-1]
+				last.Elements = append(last.Elements, element)
+				last.SpacingEnd = element.EndIndex
+			}
+		case generateLineKey(text) != "":
+			pairs = append(pairs, bilingualPair{
+				Line:       DocumentLine{Number: len(pairs) + 1, Text: text, Type: classifyLineType(text), Key: generateLineKey(text)},
+				Elements:   []*docs.StructuralElement{element},
+				SpacingEnd: element.EndIndex,
+			})
+			open = true
+		default:
+			open = false
+		}
+	}
+	return pairs
+}
+
+// pairLines returns the English line of every pair
+func pairLines(pairs []bilingualPair) []DocumentLine {
+	lines := make([]DocumentLine, len(pairs))
+	for i, pair := range pairs {
+		lines[i] = pair.Line
+	}
+	return lines
+}
+
+// bodyEndIndex returns the index just past the last element of the body
+func bodyEndIndex(doc *docs.Document) int64 {
+	end := int64(1)
+	if doc.Body != nil {
+		for _, element := range doc.Body.Content {
+			end = max(end, element.EndIndex)
+		}
+	}
+	return end
+}
+
+// planBilingualUpdate builds the edits that delete the pairs of removed source lines and insert
+// the added lines with their translations. chinese and tabs are keyed by new line index. Hunks
+// are applied from the end of the doc so that every index refers to the unmodified doc.
+func planBilingualUpdate(doc *docs.Document, oldLines, newLines []DocumentLine, hunks []sourceHunk, chinese map[int]string, tabs map[int]int) *updatePlan {
+	pairs := collectBilingualPairs(doc)
+	oldToPair, _ := alignLineKeys(oldLines, pairLines(pairs))
+	bodyEnd := bodyEndIndex(doc)
+
+	// Follow the doc's own convention of an empty line after each pair
+	spaced := 0
+	for _, pair := range pairs {
+		if pair.SpacingEnd > pair.Elements[len(pair.Elements)-1].EndIndex {
+			spaced++
+		}
+	}
+	useSpacing := len(pairs) > 0 && spaced*2 > len(pairs)
+
+	plan := &updatePlan{}
+	for h := len(hunks) - 1; h >= 0; h-- {
+		hunk := hunks[h]
+
+		var removed []bilingualPair
+		for _, r := range hunk.Removed {
+			if p := oldToPair[r]; p >= 0 {
+				removed = append(removed, pairs[p])
+			} else {
+				plan.Missing = append(plan.Missing, oldLines[r])
+			}
+		}
+
+		// Insert where the removed lines were, else before the next unchanged line still in the doc
+		pos := bodyEnd
+		if len(removed) > 0 {
+			pos = removed[0].Elements[0].StartIndex
+		} else if hunk.Next >= 0 {
+			for k := hunk.Next; k < len(oldLines); k++ {
+				if p := oldToPair[k]; p >= 0 {
+					pos = pairs[p].Elements[0].StartIndex
+					break
+				}
+			}
+		}
+
+		for r := len(removed) - 1; r >= 0; r-- {
+			start, end := removed[r].Elements[0].StartIndex, removed[r].SpacingEnd
+			// The last newline of the body can't be deleted
+			end = min(end, bodyEnd-1)
+			if end > start {
+				plan.Requests = append(plan.Requests, &docs.Request{DeleteContentRange: &docs.DeleteContentRangeRequest{
+					Range: &docs.Range{StartIndex: start, EndIndex: end},
+				}})
+			}
+		}
+
+		if len(hunk.Added) == 0 {
+			continue
+		}
+		var sb strings.Builder
+		for _, j := range hunk.Added {
+			indent := strings.Repeat("\t", tabs[j])
+			sb.WriteString(indent + newLines[j].Text + "\n")
+			if zh := chinese[j]; zh != "" {
+				for _, line := range strings.Split(zh, "\n") {
+					sb.WriteString(indent + line + "\n")
+				}
+			}
+			if useSpacing {
+				sb.WriteString("\n")
+			}
+			plan.Inserted = append(plan.Inserted, j)
+		}
+		text := sb.String()
+		// The inserted paragraphs start at pos and end before the paragraph they were inserted into
+		start, end := pos, pos+int64(len(utf16.Encode([]rune(text))))
+		if pos >= bodyEnd {
+			// Appending: start a new paragraph after the last one instead of ending with a newline
+			pos = bodyEnd - 1
+			text = "\n" + strings.TrimSuffix(text, "\n")
+			start, end = bodyEnd, bodyEnd+int64(len(utf16.Encode([]rune(text))))
+		}
+		plan.Requests = append(plan.Requests, &docs.Request{InsertText: &docs.InsertTextRequest{
+			Location: &docs.Location{Index: pos},
+			Text:     text,
+		}})
+		plan.Requests = append(plan.Requests, plainParagraphRequests(start, end)...)
+	}
+	sort.Ints(plan.Inserted)
+	return plan
+}
+
+// translateAddedLines translates the added source lines and returns the Chinese text per new line index
+func translateAddedLines(ctx context.Context, tr *translator, newLines []DocumentLine, hunks []sourceHunk) (map[int]string, error) {
+	var added []int
+	var texts []string
+	for _, hunk := range hunks {
+		for _, j := range hunk.Added {
+			added = append(added, j)
+			texts = append(texts, newLines[j].Text)
+		}
+	}
+	chinese := make(map[int]string)
+	if len(added) == 0 {
+		return chinese, nil
+	}
+
+	translation, _, err := tr.translate(ctx, strings.Join(texts, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	// Queue translations per key so that repeated lines are consumed in order
+	translated := make(map[string][]string)
+	for _, pair := range parseTranslationPairs(translation) {
+		key := generateLineKey(pair.English)
+		translated[key] = append(translated[key], pair.Chinese)
+	}
+	for _, j := range added {
+		key := newLines[j].Key
+		if len(translated[key]) == 0 || translated[key][0] == "" {
+			slog.Warn("no translation for added line", "line", newLines[j].Number, "text", truncateText(newLines[j].Text, 60))
+			continue
+		}
+		chinese[j] = translated[key][0]
+		translated[key] = translated[key][1:]
+	}
+	return chinese, nil
+}
+
+// latestUpdatableRun returns the most recent alternating-line run whose translation was written with a source snapshot
+func latestUpdatableRun() (*runManifest, error) {
+	entries, err := os.ReadDir(runsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	// Run IDs start with their creation time, so the names sort chronologically
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].IsDir() {
+			continue
+		}
+		m, err := loadRunManifest(entries[i].Name())
+		if err != nil {
+			continue
+		}
+		if m.SourceSnapshot != "" && m.OutputDocID != "" && m.isCompleted(StepWriteOutput) && m.OutputMode != OutputModeTable {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("no e2e run with a written output doc and a source snapshot found in %s", runsDir)
+}
+
+// printSourceHunks lists the source edits found by update
+func printSourceHunks(oldLines, newLines []DocumentLine, hunks []sourceHunk) {
+	for _, hunk := range hunks {
+		for _, i := range hunk.Removed {
+			fmt.Printf("- %4d  %s\n", oldLines[i].Number, truncateText(oldLines[i].Text, 80))
+		}
+		for _, j := range hunk.Added {
+			fmt.Printf("+ %4d  %s\n", newLines[j].Number, truncateText(newLines[j].Text, 80))
+		}
+	}
+}
+
+// reimportLocalInput imports the local input file of a file-based run again and returns the new
+// doc's URL and ID
+func reimportLocalInput(ctx context.Context, c *clients, path, folderID string) (string, string, error) {
+	url, err := importLocalInput(ctx, c, path, folderID)
+	if err != nil {
+		return "", "", err
+	}
+	docID := extractDocumentID(url)
+	if docID == "" {
+		return "", "", fmt.Errorf("unable to parse doc ID of the imported file from %s", url)
+	}
+	return url, docID, nil
+}
+
+// trashTemporaryDoc moves a doc made only for one command, such as an import of a local file, to the
+// trash; failures are only logged since the doc is a leftover copy
+func trashTemporaryDoc(ctx context.Context, c *clients, docID string) {
+	srv, err := c.UserDrive(ctx)
+	if err == nil {
+		_, err = srv.Files.Update(docID, &drive.File{Trashed: true}).SupportsAllDrives(true).Context(ctx).Do()
+	}
+	if err != nil {
+		slog.Warn("unable to move temporary doc to the trash", "doc_id", docID, "err", err)
+	}
+}
+
+// updateBilingualDoc re-translates the source lines edited since an e2e run and patches the run's output doc
+func updateBilingualDoc(c *clients, cfg *appConfig, runID string, noCache, dryRun bool) {
+	ctx := context.Background()
+
+	var manifest *runManifest
+	var err error
+	if runID != "" {
+		manifest, err = loadRunManifest(runID)
+	} else {
+		manifest, err = latestUpdatableRun()
+	}
+	if err != nil {
+		logFatal("failed to load run manifest", "err", err)
+	}
+	if manifest.SourceSnapshot == "" || !manifest.isCompleted(StepWriteOutput) {
+		logFatal("run has no written output doc with a source snapshot; run e2e again", "run_id", manifest.RunID)
+	}
+	if manifest.OutputMode == OutputModeTable {
+		logFatal("update only patches alternating-line output, not the table layout; run e2e again", "run_id", manifest.RunID)
+	}
+	slog.Info("updating run", "run_id", manifest.RunID, "output", manifest.OutputURL)
+
+	oldLines, err := readSourceSnapshot(manifest.SourceSnapshot)
+	if err != nil {
+		logFatal("failed to read source snapshot", "err", err)
+	}
+	sourceURL, sourceDocID := manifest.Input, manifest.InputDocID
+	if manifest.InputFile != "" {
+		// The imported doc is a snapshot of the file; import the file again to see the pastor's edits
+		sourceURL, sourceDocID, err = reimportLocalInput(ctx, c, manifest.InputFile, cfg.OutputFolderID)
+		if err != nil {
+			logFatal("failed to re-import local input file", "path", manifest.InputFile, "err", err)
+		}
+		slog.Info("re-imported local input file", "path", manifest.InputFile, "doc_id", sourceDocID)
+	}
+	// discardImport trashes the new import when the run keeps using the previous one
+	discardImport := func() {
+		if sourceDocID != manifest.InputDocID {
+			trashTemporaryDoc(ctx, c, sourceDocID)
+		}
+	}
+	sourceDoc, err := getDocument(ctx, c.Docs, sourceDocID)
+	if err != nil {
+		logFatal("unable to retrieve source document", "err", err)
+	}
+	newLines, features, err := collectSourceLines(sourceDoc)
+	if err != nil {
+		logFatal("unable to read source document lines", "err", err)
+	}
+
+	hunks := diffSourceLines(oldLines, newLines)
+	if len(hunks) == 0 {
+		discardImport()
+		fmt.Println("The source has not changed since the last translation.")
+		return
+	}
+	printSourceHunks(oldLines, newLines, hunks)
+	if dryRun {
+		discardImport()
+		return
+	}
+
+	tr, err := newTranslator(cfg, c.Auth, noCache)
+	if err != nil {
+		logFatal("failed to set up translation", "err", err)
+	}
+	chinese, err := translateAddedLines(ctx, tr, newLines, hunks)
+	if err != nil {
+		logFatal("failed to translate added lines", "err", err)
+	}
+
+	targetDoc, err := getDocument(ctx, c.Docs, manifest.OutputDocID)
+	if err != nil {
+		logFatal("unable to retrieve bilingual document", "err", err)
+	}
+	tabs := make(map[int]int)
+	for j, f := range features {
+		tabs[j] = f.LeadingTabs
+	}
+	plan := planBilingualUpdate(targetDoc, oldLines, newLines, hunks, chinese, tabs)
+	for _, line := range plan.Missing {
+		slog.Warn("removed source line not found in the bilingual doc, left as is", "line", line.Number, "text", truncateText(line.Text, 60))
+	}
+	if len(plan.Requests) > 0 {
+		if _, err := batchUpdateDocument(ctx, c.Docs, manifest.OutputDocID, &docs.BatchUpdateDocumentRequest{Requests: plan.Requests}); err != nil {
+			logFatal("failed to update bilingual document", "err", err)
+		}
+	}
+
+	// Format only the inserted pairs, the way sync-format would
+	targetDoc, err = getDocument(ctx, c.Docs, manifest.OutputDocID)
+	if err != nil {
+		logFatal("unable to re-fetch bilingual document", "err", err)
+	}
+	pairs := collectBilingualPairs(targetDoc)
+	newToPair, _ := alignLineKeys(newLines, pairLines(pairs))
+	for _, j := range plan.Inserted {
+		p := newToPair[j]
+		if p < 0 {
+			slog.Warn("inserted line not found for formatting", "line", newLines[j].Number)
+			continue
+		}
+		for _, element := range pairs[p].Elements {
+			if err := applyFormattingToRange(ctx, c.Docs, manifest.OutputDocID, element.StartIndex, element.EndIndex, features[j]); err != nil {
+				slog.Warn("failed to apply formatting", "line", newLines[j].Number, "err", err)
+			}
+		}
+	}
+
+	if err := writeSourceSnapshot(manifest.SourceSnapshot, newLines); err != nil {
+		logFatal("failed to save source snapshot", "err", err)
+	}
+	if sourceDocID != manifest.InputDocID {
+		trashTemporaryDoc(ctx, c, manifest.InputDocID)
+		manifest.InputDocID = sourceDocID
+		manifest.Input = sourceURL
+	}
+	if _, revision, err := getDocumentInfo(ctx, c.Docs, manifest.InputDocID); err == nil {
+		manifest.InputRevision = revision
+	}
+	if err := saveRunManifest(manifest); err != nil {
+		logFatal("failed to save run manifest", "err", err)
+	}
+	fmt.Printf("Updated %s: %d line(s) inserted, %d removed line(s) not found\n", manifest.OutputURL, len(plan.Inserted), len(plan.Missing))
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// applyRequests replays insert and delete requests on the plain text of a document built by
+// buildParagraphDocument, whose index 1 is the first character
+func applyRequests(text string, requests []*docs.Request) string {
+	runes := []rune(text)
+	for _, req := range requests {
+		switch {
+		case req.DeleteContentRange != nil:
+			r := req.DeleteContentRange.Range
+			runes = slices.Delete(runes, int(r.StartIndex-1), int(r.EndIndex-1))
+		case req.InsertText != nil:
+			at := int(req.InsertText.Location.Index - 1)
+			runes = slices.Insert(runes, at, []rune(req.InsertText.Text)...)
+		}
+	}
+	return string(runes)
+}
+
+func TestDiffSourceLines(t *testing.T) {
+	oldLines := buildDocumentLines("A line", "B line", "C line", "D line")
+	newLines := buildDocumentLines("A line", "B line, edited", "C line", "E line", "D line", "F line")
+
+	hunks := diffSourceLines(oldLines, newLines)
+	expected := []sourceHunk{
+		{Removed: []int{1}, Added: []int{1}, Previous: 0, Next: 2},
+		{Added: []int{3}, Previous: 2, Next: 3},
+		{Added: []int{5}, Previous: 3, Next: -1},
+	}
+	if len(hunks) != len(expected) {
+		t.Fatalf("diffSourceLines() = %+v, want %+v", hunks, expected)
+	}
+	for i := range expected {
+		got, want := hunks[i], expected[i]
+		if !slices.Equal(got.Removed, want.Removed) || !slices.Equal(got.Added, want.Added) || got.Previous != want.Previous || got.Next != want.Next {
+			t.Errorf("hunk %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	if hunks := diffSourceLines(oldLines, oldLines); len(hunks) != 0 {
+		t.Errorf("diffSourceLines() of unchanged lines = %+v", hunks)
+	}
+}
+
+func TestPlanBilingualUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   []string
+		oldLines []DocumentLine
+		newLines []DocumentLine
+		chinese  map[int]string
+		tabs     map[int]int
+		expected string
+		missing  int
+	}{
+		{
+			name:     "Change, insert and append",
+			target:   []string{"A line", "甲", "B line", "乙", "C line", "丙", "D line", "丁"},
+			oldLines: buildDocumentLines("A line", "B line", "C line", "D line"),
+			newLines: buildDocumentLines("A line", "B line, edited", "C line", "E line", "D line", "F line"),
+			chinese:  map[int]string{1: "乙，已改", 3: "戊", 5: "己"},
+			tabs:     map[int]int{3: 1},
+			expected: "A line\n甲\nB line, edited\n乙，已改\nC line\n丙\n\tE line\n\t戊\nD line\n丁\nF line\n己\n",
+		},
+		{
+			name:     "Delete a pair with its spacing",
+			target:   []string{"A line", "甲", "", "B line", "乙", "丙", "", "C line", "丁", ""},
+			oldLines: buildDocumentLines("A line", "B line", "C line"),
+			newLines: buildDocumentLines("A line", "C line"),
+			expected: "A line\n甲\n\nC line\n丁\n\n",
+		},
+		{
+			name:     "Insert follows the doc's spacing",
+			target:   []string{"A line", "甲", "", "B line", "乙", ""},
+			oldLines: buildDocumentLines("A line", "B line"),
+			newLines: buildDocumentLines("A line", "New line", "B line"),
+			chinese:  map[int]string{1: "新的一行"},
+			expected: "A line\n甲\n\nNew line\n新的一行\n\nB line\n乙\n\n",
+		},
+		{
+			name:     "Removed line edited by a reviewer",
+			target:   []string{"A line", "甲", "B line (reviewed)", "乙"},
+			oldLines: buildDocumentLines("A line", "B line"),
+			newLines: buildDocumentLines("A line"),
+			expected: "A line\n甲\nB line (reviewed)\n乙\n",
+			missing:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := buildParagraphDocument(tt.target...)
+			hunks := diffSourceLines(tt.oldLines, tt.newLines)
+			plan := planBilingualUpdate(doc, tt.oldLines, tt.newLines, hunks, tt.chinese, tt.tabs)
+
+			result := applyRequests(strings.Join(tt.target, "\n")+"\n", plan.Requests)
+			if result != tt.expected {
+				t.Errorf("updated doc =\n%q\nwant\n%q", result, tt.expected)
+			}
+			if len(plan.Missing) != tt.missing {
+				t.Errorf("missing = %+v, want %d", plan.Missing, tt.missing)
+			}
+		})
+	}
+}
+
+func TestPlanBilingualUpdateParagraphStyle(t *testing.T) {
+	target := []string{"A line", "甲", "B line", "乙"}
+	doc := buildParagraphDocument(target...)
+	// B line is a bulleted heading; lines inserted before it must not become headings too
+	heading := doc.Body.Content[2]
+	heading.Paragraph.ParagraphStyle.NamedStyleType = "HEADING_2"
+	heading.Paragraph.Bullet = &docs.Bullet{ListId: "list"}
+
+	oldLines := buildDocumentLines("A line", "B line")
+	newLines := buildDocumentLines("A line", "New line", "B line", "Last line")
+	chinese := map[int]string{1: "新的一行", 3: "最后一行"}
+	plan := planBilingualUpdate(doc, oldLines, newLines, diffSourceLines(oldLines, newLines), chinese, nil)
+
+	// Each insert is followed by a reset of exactly the paragraphs it added, in the doc as it is
+	// after the insert
+	text := strings.Join(target, "\n") + "\n"
+	var ranges []string
+	for i, req := range plan.Requests {
+		text = applyRequests(text, []*docs.Request{req})
+		if req.InsertText == nil {
+			continue
+		}
+		if i+2 >= len(plan.Requests) || plan.Requests[i+1].UpdateParagraphStyle == nil || plan.Requests[i+2].DeleteParagraphBullets == nil {
+			t.Fatalf("insert %d is not followed by a paragraph style and bullet reset: %+v", i, plan.Requests)
+		}
+		style := plan.Requests[i+1].UpdateParagraphStyle
+		if style.ParagraphStyle.NamedStyleType != "NORMAL_TEXT" || style.Fields != "namedStyleType" {
+			t.Errorf("paragraph style request = %+v", style)
+		}
+		if bullets := plan.Requests[i+2].DeleteParagraphBullets.Range; bullets.StartIndex != style.Range.StartIndex || bullets.EndIndex != style.Range.EndIndex {
+			t.Errorf("bullet range %+v differs from style range %+v", bullets, style.Range)
+		}
+		runes := []rune(text)
+		ranges = append(ranges, string(runes[style.Range.StartIndex-1:style.Range.EndIndex-1]))
+	}
+	want := []string{"Last line\n最后一行\n", "New line\n新的一行\n"}
+	if !slices.Equal(ranges, want) {
+		t.Errorf("reset ranges cover %q, want %q", ranges, want)
+	}
+}
+
+func TestSourceSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", sourceSnapshotFile)
+	lines := buildDocumentLines("Call to Worship", "Sermon")
+	if err := writeSourceSnapshot(path, lines); err != nil {
+		t.Fatalf("writeSourceSnapshot() error = %v", err)
+	}
+	loaded, err := readSourceSnapshot(path)
+	if err != nil {
+		t.Fatalf("readSourceSnapshot() error = %v", err)
+	}
+	if !slices.Equal(loaded, lines) {
+		t.Errorf("readSourceSnapshot() = %+v, want %+v", loaded, lines)
+	}
+	if _, err := readSourceSnapshot(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Errorf("readSourceSnapshot() of a missing file error = %v", err)
+	}
+}